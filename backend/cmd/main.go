@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"realtimeforum/backend/handlers"
+	"realtimeforum/backend/mailer"
 	"realtimeforum/backend/models"
 	"realtimeforum/backend/router"
 	"realtimeforum/backend/ws"
 	"realtimeforum/database"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -27,6 +30,24 @@ func main() {
 
 	handlers.SetHub(hub)
 
+	digestInterval := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("DIGEST_INTERVAL")); err == nil && d > 0 {
+		digestInterval = d
+	}
+	go handlers.RunDigestScheduler(mailer.FromEnv(), digestInterval)
+
+	draftInterval := 30 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("DRAFT_PUBLISH_INTERVAL")); err == nil && d > 0 {
+		draftInterval = d
+	}
+	go handlers.RunDraftPublisher(draftInterval)
+
+	viewFlushInterval := 10 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("VIEW_FLUSH_INTERVAL")); err == nil && d > 0 {
+		viewFlushInterval = d
+	}
+	go handlers.RunViewFlusher(viewFlushInterval)
+
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
@@ -40,12 +61,33 @@ func main() {
 	mux.HandleFunc("/api/contacts", handlers.GetAllUsersHandler)
 	mux.HandleFunc("/api/user/id", handlers.GetUserIDHandler)
 	mux.HandleFunc("/api/categories", handlers.CategoriesHandler)
+	mux.HandleFunc("/api/categories/", handlers.CategorySubresourceRouter)
+	mux.HandleFunc("/api/forum", handlers.ForumIndexHandler)
+	mux.HandleFunc("/api/feed", handlers.FeedHandler)
+	mux.HandleFunc("/api/tags", handlers.TagsHandler)
 	mux.HandleFunc("/api/posts", handlers.PostsHandler)
+	mux.HandleFunc("/api/posts.rss", handlers.PostsRSSHandler)
+	mux.HandleFunc("/api/posts.atom", handlers.PostsAtomHandler)
+	mux.HandleFunc("/api/posts/trending", handlers.TrendingPostsHandler)
 	mux.HandleFunc("/api/posts/", handlers.PostSubresourceRouter)
+	mux.HandleFunc("/api/bookmarks", handlers.BookmarksHandler)
+	mux.HandleFunc("/api/bookmarks/folders", handlers.BookmarkFoldersHandler)
+	mux.HandleFunc("/api/drafts", handlers.DraftsHandler)
+	mux.HandleFunc("/api/drafts/", handlers.DraftSubresourceRouter)
 	mux.HandleFunc("/api/comments/", handlers.CommentSubresourceRouter)
+	mux.HandleFunc("/api/mentions", handlers.MentionsHandler)
+	mux.HandleFunc("/api/notifications", handlers.NotificationsHandler)
+	mux.HandleFunc("/api/notifications/unread-count", handlers.UnreadNotificationCountHandler)
+	mux.HandleFunc("/api/notifications/read-all", handlers.MarkAllNotificationsReadHandler)
+	mux.HandleFunc("/api/notifications/", handlers.NotificationSubresourceRouter)
+	mux.HandleFunc("/api/preferences", handlers.PreferencesHandler)
+	mux.HandleFunc("/api/preferences/", handlers.PreferencesSubresourceRouter)
 	mux.HandleFunc("/api/profile", handlers.ProfileHandler)
 	mux.HandleFunc("/api/private-messages", handlers.GetPrivateMessagesHandler)
 	mux.HandleFunc("/api/private-messages/send", handlers.SendPrivateMessageHandler)
+	mux.HandleFunc("/api/private-messages/starred", handlers.GetStarredMessagesHandler)
+	mux.HandleFunc("/api/private-messages/pinned", handlers.GetPinnedMessagesHandler)
+	mux.HandleFunc("/api/private-messages/", handlers.PrivateMessageSubresourceRouter)
 	mux.HandleFunc("/api/typing/start", handlers.StartTypingHandler)
 	mux.HandleFunc("/api/typing/stop", handlers.StopTypingHandler)
 
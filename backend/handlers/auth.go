@@ -3,12 +3,8 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
-	"io"
 	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
 	"realtimeforum/backend/models"
 	"regexp"
 	"strings"
@@ -165,57 +161,10 @@ func saveProfilePicture(r *http.Request) (string, error) {
     }
     defer file.Close()
 
-    // Validate file size
-    if handler.Size > 5<<20 { // 5MB
-        return "", fmt.Errorf("file too large: max size is 5MB")
-    }
-
-    // Validate file type
-    buff := make([]byte, 512)
-    _, err = file.Read(buff)
-    if err != nil {
-        return "", err
-    }
-
-    filetype := http.DetectContentType(buff)
-    if !strings.HasPrefix(filetype, "image/") {
-        return "", fmt.Errorf("invalid file type: only images are allowed")
-    }
-
-    _, err = file.Seek(0, 0)
-    if err != nil {
-        return "", err
-    }
-
-    // Create uploads directory relative to your project root
-    uploadDir := "frontend/assets/uploads/profile_pictures"
-    if err := os.MkdirAll(uploadDir, 0755); err != nil {
-        return "", err
-    }
-
-    // Generate unique filename
-    fileExt := strings.TrimPrefix(strings.Split(handler.Header.Get("Content-Type"), "/")[1], "x-")
-    if fileExt == "jpeg" {
-        fileExt = "jpg"
-    }
-    filename := fmt.Sprintf("%d_%s.%s", time.Now().UnixNano(), GenerateRandomString(8), fileExt)
-    filepath := filepath.Join(uploadDir, filename)
-
-    // Create the file
-    dst, err := os.Create(filepath)
-    if err != nil {
-        return "", err
-    }
-    defer dst.Close()
-
-    // Copy the uploaded file to the destination
-    _, err = io.Copy(dst, file)
-    if err != nil {
-        return "", err
-    }
-
-    // Return the URL path (not the filesystem path)
-    return "/assets/uploads/profile_pictures/" + filename, nil
+    // Size and type checks, unique filename; returns the URL path (not the filesystem path)
+    urlPath, _, err := storeImageUpload(file, handler,
+        "frontend/assets/uploads/profile_pictures", "/assets/uploads/profile_pictures/", nil)
+    return urlPath, err
 }
 
 func GenerateRandomString(length int) string {
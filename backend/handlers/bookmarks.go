@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxBookmarkFolderLength = 50
+
+type bookmarkFolderDTO struct {
+	Folder string `json:"folder"`
+	Count  int    `json:"count"`
+}
+
+// attachBookmarks sets Bookmarked (and the folder and time) on the posts
+// userID has saved. It is a no-op for logged-out readers.
+func attachBookmarks(posts []postDTO, ids []int64, userID int64) error {
+	if userID == 0 {
+		return nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(ids)), ",")
+	args := []any{userID}
+	for _, v := range ids {
+		args = append(args, v)
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+SELECT post_id, folder, created_at FROM bookmarks
+WHERE user_id = ? AND post_id IN (%s)`, placeholders), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type saved struct {
+		folder string
+		at     time.Time
+	}
+	byPost := make(map[int64]saved)
+	for rows.Next() {
+		var pid int64
+		var s saved
+		if err := rows.Scan(&pid, &s.folder, &s.at); err != nil {
+			return err
+		}
+		byPost[pid] = s
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range posts {
+		if s, ok := byPost[posts[i].PostID]; ok {
+			posts[i].Bookmarked = true
+			posts[i].BookmarkFolder = s.folder
+			at := s.at
+			posts[i].BookmarkedAt = &at
+		}
+	}
+	return nil
+}
+
+// /api/posts/{id}/bookmark
+//
+//	PUT  { "folder": "..." }   saves the post, or moves it to another folder (body optional)
+//	DELETE                     removes it from the saved posts
+func handleBookmark(w http.ResponseWriter, r *http.Request, postID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		var p struct {
+			Folder string `json:"folder"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil && err != io.EOF {
+			sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		folder := strings.TrimSpace(p.Folder)
+		if utf8.RuneCountInString(folder) > maxBookmarkFolderLength {
+			sendErrorResponse(w, fmt.Sprintf("Folder names are at most %d characters", maxBookmarkFolderLength), http.StatusBadRequest)
+			return
+		}
+
+		var exists int
+		if err := db.QueryRow(`SELECT 1 FROM posts WHERE post_id = ?`, postID).Scan(&exists); err != nil {
+			if err == sql.ErrNoRows {
+				sendErrorResponse(w, "Post not found", http.StatusNotFound)
+				return
+			}
+			sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := db.Exec(`INSERT INTO bookmarks (user_id, post_id, folder) VALUES (?, ?, ?)
+			ON CONFLICT(user_id, post_id) DO UPDATE SET folder = excluded.folder`,
+			sess.UserID, postID, folder); err != nil {
+			sendErrorResponse(w, "DB error (save bookmark)", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success":    true,
+			"post_id":    postID,
+			"bookmarked": true,
+			"folder":     folder,
+		})
+
+	case http.MethodDelete:
+		if _, err := db.Exec(`DELETE FROM bookmarks WHERE user_id = ? AND post_id = ?`, sess.UserID, postID); err != nil {
+			sendErrorResponse(w, "DB error (remove bookmark)", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success":    true,
+			"post_id":    postID,
+			"bookmarked": false,
+		})
+
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET /api/bookmarks?folder=&before_id=&limit= -> the session user's saved
+// posts, most recently saved first. folder narrows to one folder; an empty
+// folder= means unfiled posts. nextCursor is the before_id for the next page,
+// 0 once a page comes back short.
+func BookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	beforeID := toInt64(q.Get("before_id"), 0)
+
+	query := postColumns + `, b.bookmark_id` + postFrom + `JOIN bookmarks b ON b.post_id = p.post_id
+WHERE b.user_id = ?`
+	args := []any{sess.UserID, sess.UserID}
+	if _, ok := q["folder"]; ok {
+		query += ` AND b.folder = ?`
+		args = append(args, strings.TrimSpace(q.Get("folder")))
+	}
+	if beforeID > 0 {
+		query += ` AND b.bookmark_id < ?`
+		args = append(args, beforeID)
+	}
+	query += `
+ORDER BY b.bookmark_id DESC
+LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list bookmarks)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	posts := []postDTO{}
+	var postIDs []int64
+	var nextBefore int64
+	for rows.Next() {
+		p, err := scanPost(rows, &nextBefore)
+		if err != nil {
+			sendErrorResponse(w, "DB error (scan)", http.StatusInternalServerError)
+			return
+		}
+		posts = append(posts, p)
+		postIDs = append(postIDs, p.PostID)
+	}
+	if err := rows.Err(); err != nil {
+		sendErrorResponse(w, "DB error (list bookmarks)", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+	if len(posts) < limit {
+		nextBefore = 0
+	}
+
+	if len(postIDs) > 0 {
+		if err := attachCategories(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load categories)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachTags(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load tags)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachPolls(posts, postIDs, sess.UserID); err != nil {
+			sendErrorResponse(w, "DB error (load polls)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachBookmarks(posts, postIDs, sess.UserID); err != nil {
+			sendErrorResponse(w, "DB error (load bookmarks)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"data":       posts,
+		"nextCursor": nextBefore,
+		"count":      len(posts),
+	})
+}
+
+// GET /api/bookmarks/folders -> the session user's folders with post counts;
+// unfiled posts are reported under "".
+func BookmarkFoldersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := db.Query(`SELECT folder, COUNT(*) FROM bookmarks WHERE user_id = ?
+		GROUP BY folder ORDER BY folder COLLATE NOCASE`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list folders)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []bookmarkFolderDTO{}
+	for rows.Next() {
+		var f bookmarkFolderDTO
+		if err := rows.Scan(&f.Folder, &f.Count); err != nil {
+			sendErrorResponse(w, "DB error (scan folder)", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, f)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    items,
+	})
+}
@@ -5,8 +5,15 @@ import (
 	"net/http"
 )
 
+// GET /api/categories[?include_archived=true] -> categories in display order
+// POST /api/categories { "name": "...", "description": "..." } (admins)
 func CategoriesHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		handleCreateCategory(w, r)
+		return
+	default:
 		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -17,17 +24,21 @@ func CategoriesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := db.Query(`SELECT category_id, name FROM categories ORDER BY name ASC`)
+	query := categorySelect
+	if r.URL.Query().Get("include_archived") != "true" {
+		query += `WHERE archived_at IS NULL `
+	}
+	rows, err := db.Query(query + `ORDER BY position ASC, name ASC`)
 	if err != nil {
 		sendErrorResponse(w, "DB error (list categories)", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var cats []categoryDTO
+	cats := []categoryDetailDTO{}
 	for rows.Next() {
-		var c categoryDTO
-		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			sendErrorResponse(w, "DB error (scan category)", http.StatusInternalServerError)
 			return
 		}
@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	minCategoryNameLength        = 2
+	maxCategoryNameLength        = 40
+	maxCategoryDescriptionLength = 300
+)
+
+type categoryDetailDTO struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+	Archived    bool   `json:"archived"`
+}
+
+type categoryPayload struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+const categorySelect = `
+SELECT category_id, name, description, position, archived_at IS NOT NULL
+FROM categories
+`
+
+// Statements that move everything filed under category ?2 to category ?1
+// when two categories are merged. post_drafts keeps category ids as JSON.
+var categoryMerge = []string{
+	`INSERT OR IGNORE INTO post_categories (post_id, category_id) SELECT post_id, ?1 FROM post_categories WHERE category_id = ?2`,
+	`DELETE FROM post_categories WHERE category_id = ?2`,
+	`INSERT OR IGNORE INTO muted_categories (user_id, category_id, created_at) SELECT user_id, ?1, created_at FROM muted_categories WHERE category_id = ?2`,
+	`DELETE FROM muted_categories WHERE category_id = ?2`,
+	`INSERT OR IGNORE INTO category_follows (user_id, category_id, created_at) SELECT user_id, ?1, created_at FROM category_follows WHERE category_id = ?2`,
+	`DELETE FROM category_follows WHERE category_id = ?2`,
+	`UPDATE posts SET pinned_category_id = ?1 WHERE pinned_category_id = ?2`,
+	`DELETE FROM category_visits WHERE category_id = ?2`,
+	`UPDATE post_drafts SET categories = (
+		SELECT json_group_array(DISTINCT CASE WHEN value = ?2 THEN ?1 ELSE value END) FROM json_each(post_drafts.categories))
+	WHERE EXISTS (SELECT 1 FROM json_each(post_drafts.categories) WHERE value = ?2)`,
+}
+
+func scanCategory(row interface{ Scan(...any) error }) (categoryDetailDTO, error) {
+	var c categoryDetailDTO
+	err := row.Scan(&c.ID, &c.Name, &c.Description, &c.Position, &c.Archived)
+	return c, err
+}
+
+func loadCategory(categoryID int64) (categoryDetailDTO, error) {
+	return scanCategory(db.QueryRow(categorySelect+`WHERE category_id = ?`, categoryID))
+}
+
+// validateCategoryIDs reports the first id that does not name a live category.
+// post_categories has no enforced foreign key, so this is the only check.
+func validateCategoryIDs(ids []int64) error {
+	for _, id := range ids {
+		var name string
+		var archived bool
+		err := db.QueryRow(`SELECT name, archived_at IS NOT NULL FROM categories WHERE category_id = ?`, id).Scan(&name, &archived)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Unknown category id %d", id)
+		}
+		if err != nil {
+			return err
+		}
+		if archived {
+			return fmt.Errorf("Category %q is archived", name)
+		}
+	}
+	return nil
+}
+
+// normalizeCategoryFields trims p and checks lengths; a nil field is left alone.
+func normalizeCategoryFields(p *categoryPayload) error {
+	if p.Name != nil {
+		name := strings.TrimSpace(*p.Name)
+		if n := utf8.RuneCountInString(name); n < minCategoryNameLength || n > maxCategoryNameLength {
+			return fmt.Errorf("Category names must be %d to %d characters", minCategoryNameLength, maxCategoryNameLength)
+		}
+		p.Name = &name
+	}
+	if p.Description != nil {
+		desc := strings.TrimSpace(*p.Description)
+		if utf8.RuneCountInString(desc) > maxCategoryDescriptionLength {
+			return fmt.Errorf("Descriptions are at most %d characters", maxCategoryDescriptionLength)
+		}
+		p.Description = &desc
+	}
+	return nil
+}
+
+// categoryNameTaken reports whether another category already uses name, ignoring case.
+func categoryNameTaken(name string, exceptID int64) (bool, error) {
+	var one int
+	err := db.QueryRow(`SELECT 1 FROM categories WHERE name = ? COLLATE NOCASE AND category_id != ?`, name, exceptID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// requireAdmin writes the error response and returns false unless the
+// session user is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if !isAdmin(sess.UserID) {
+		sendErrorResponse(w, "Only admins can manage categories", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func emitCategoryUpdate(action string, extra map[string]any) {
+	payload := map[string]any{"action": action}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	Emit("category.updated", payload)
+}
+
+// POST /api/categories
+func handleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var p categoryPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if p.Name == nil {
+		sendErrorResponse(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if err := normalizeCategoryFields(&p); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	description := ""
+	if p.Description != nil {
+		description = *p.Description
+	}
+
+	if taken, err := categoryNameTaken(*p.Name, 0); err != nil {
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	} else if taken {
+		sendErrorResponse(w, "A category with that name already exists", http.StatusConflict)
+		return
+	}
+
+	res, err := db.Exec(`INSERT INTO categories (name, description, position)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM categories))`, *p.Name, description)
+	if err != nil {
+		sendErrorResponse(w, "DB error (create category)", http.StatusInternalServerError)
+		return
+	}
+	id, _ := res.LastInsertId()
+
+	c, err := loadCategory(id)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load category)", http.StatusInternalServerError)
+		return
+	}
+	emitCategoryUpdate("created", map[string]any{"category_id": id, "category": c})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    c,
+	})
+}
+
+// /api/categories/order          PUT { "category_ids": [3, 1, 2] } sets the display order
+// /api/categories/{id}           GET, PATCH { "name": "...", "description": "..." }
+// /api/categories/{id}/archive   PUT archives (hidden, closed to new posts), DELETE restores
+// /api/categories/{id}/merge     POST { "into": 2 } moves its posts to another category and deletes it
+// /api/categories/{id}/follow    PUT follows the category, DELETE unfollows it
+//
+// Everything but GET and follow is for admins.
+func CategorySubresourceRouter(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/categories/"), "/")
+	if parts[0] == "order" && len(parts) == 1 {
+		handleReorderCategories(w, r)
+		return
+	}
+
+	categoryID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || categoryID <= 0 {
+		sendErrorResponse(w, "Invalid category id", http.StatusBadRequest)
+		return
+	}
+	c, err := loadCategory(categoryID)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (load category)", http.StatusInternalServerError)
+		return
+	}
+
+	sub := ""
+	if len(parts) > 1 {
+		sub = parts[1]
+	}
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": c})
+		return
+	case sub == "follow":
+		handleFollowCategory(w, r, c)
+		return
+	case sub == "" && r.Method == http.MethodPatch,
+		sub == "archive" && (r.Method == http.MethodPut || r.Method == http.MethodDelete),
+		sub == "merge" && r.Method == http.MethodPost:
+	case sub == "" || sub == "archive" || sub == "merge":
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch sub {
+	case "":
+		handleUpdateCategory(w, r, c)
+	case "archive":
+		handleArchiveCategory(w, r, c)
+	case "merge":
+		handleMergeCategory(w, r, c)
+	}
+}
+
+func handleUpdateCategory(w http.ResponseWriter, r *http.Request, c categoryDetailDTO) {
+	var p categoryPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := normalizeCategoryFields(&p); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Name != nil {
+		if taken, err := categoryNameTaken(*p.Name, c.ID); err != nil {
+			sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+			return
+		} else if taken {
+			sendErrorResponse(w, "A category with that name already exists", http.StatusConflict)
+			return
+		}
+		c.Name = *p.Name
+	}
+	if p.Description != nil {
+		c.Description = *p.Description
+	}
+
+	if _, err := db.Exec(`UPDATE categories SET name = ?, description = ? WHERE category_id = ?`,
+		c.Name, c.Description, c.ID); err != nil {
+		sendErrorResponse(w, "DB error (update category)", http.StatusInternalServerError)
+		return
+	}
+	emitCategoryUpdate("updated", map[string]any{"category_id": c.ID, "category": c})
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "data": c})
+}
+
+func handleArchiveCategory(w http.ResponseWriter, r *http.Request, c categoryDetailDTO) {
+	archive := r.Method == http.MethodPut
+	var err error
+	if archive {
+		_, err = db.Exec(`UPDATE categories SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP) WHERE category_id = ?`, c.ID)
+	} else {
+		_, err = db.Exec(`UPDATE categories SET archived_at = NULL WHERE category_id = ?`, c.ID)
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (archive category)", http.StatusInternalServerError)
+		return
+	}
+	c.Archived = archive
+
+	action := "archived"
+	if !archive {
+		action = "restored"
+	}
+	emitCategoryUpdate(action, map[string]any{"category_id": c.ID, "category": c})
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "data": c})
+}
+
+func handleMergeCategory(w http.ResponseWriter, r *http.Request, c categoryDetailDTO) {
+	var p struct {
+		Into int64 `json:"into"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if p.Into == c.ID {
+		sendErrorResponse(w, "Cannot merge a category into itself", http.StatusBadRequest)
+		return
+	}
+	target, err := loadCategory(p.Into)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Target category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (load category)", http.StatusInternalServerError)
+		return
+	}
+	if target.Archived {
+		sendErrorResponse(w, "Cannot merge into an archived category", http.StatusBadRequest)
+		return
+	}
+
+	var moved int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM post_categories WHERE category_id = ?`, c.ID).Scan(&moved); err != nil {
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range categoryMerge {
+		if _, err := tx.Exec(stmt, target.ID, c.ID); err != nil {
+			sendErrorResponse(w, "DB error (merge category)", http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM categories WHERE category_id = ?`, c.ID); err != nil {
+		sendErrorResponse(w, "DB error (delete category)", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	emitCategoryUpdate("merged", map[string]any{"category_id": c.ID, "into": target.ID, "category": target})
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":     true,
+		"data":        target,
+		"merged_id":   c.ID,
+		"moved_posts": moved,
+	})
+}
+
+// PUT /api/categories/order
+func handleReorderCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var p struct {
+		CategoryIDs []int64 `json:"category_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil || len(p.CategoryIDs) == 0 {
+		sendErrorResponse(w, "category_ids must list the categories in their new order", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[int64]bool)
+	for _, id := range p.CategoryIDs {
+		if seen[id] {
+			sendErrorResponse(w, fmt.Sprintf("Category id %d is listed twice", id), http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		if _, err := loadCategory(id); err == sql.ErrNoRows {
+			sendErrorResponse(w, fmt.Sprintf("Unknown category id %d", id), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			sendErrorResponse(w, "DB error (load category)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Categories left out keep their relative order after the listed ones.
+	rest, err := loadIDs(`SELECT category_id FROM categories ORDER BY position ASC, name ASC`)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list categories)", http.StatusInternalServerError)
+		return
+	}
+	order := append([]int64{}, p.CategoryIDs...)
+	for _, id := range rest {
+		if !seen[id] {
+			order = append(order, id)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	for i, id := range order {
+		if _, err := tx.Exec(`UPDATE categories SET position = ? WHERE category_id = ?`, i+1, id); err != nil {
+			sendErrorResponse(w, "DB error (reorder categories)", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	emitCategoryUpdate("reordered", map[string]any{"category_ids": order})
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"category_ids": order,
+	})
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"realtimeforum/backend/markdown"
+	"strings"
+	"time"
+)
+
+// Shown in place of a deleted comment that still has replies.
+const deletedCommentText = "[deleted]"
+
+// Statements run when a comment is deleted, whether it is removed or kept
+// as a tombstone. As with postCleanup, foreign keys do not cascade.
+var commentCleanup = []string{
+	`DELETE FROM reactions WHERE comment_id = ?`,
+	`DELETE FROM notifications WHERE comment_id = ?`,
+	`DELETE FROM mentions WHERE comment_id = ?`,
+}
+
+type updateCommentPayload struct {
+	Content string `json:"content"`
+}
+
+func loadComment(commentID, userID int64) (commentDTO, error) {
+	rows, err := db.Query(commentSelect+`WHERE c.comment_id = ?
+GROUP BY c.comment_id`, userID, commentID)
+	if err != nil {
+		return commentDTO{}, err
+	}
+	items, err := scanComments(rows)
+	rows.Close()
+	if err != nil {
+		return commentDTO{}, err
+	}
+	if len(items) == 0 {
+		return commentDTO{}, sql.ErrNoRows
+	}
+	return items[0], nil
+}
+
+// GET /api/comments/{id}
+func handleGetComment(w http.ResponseWriter, r *http.Request, commentID int64) {
+	var userID int64
+	if sess, err := GetSession(r); err == nil {
+		userID = sess.UserID
+	}
+
+	c, err := loadComment(commentID, userID)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (load comment)", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    c,
+	})
+}
+
+// PUT/PATCH /api/comments/{id} { "content": "..." } (author and moderators)
+func handleUpdateComment(w http.ResponseWriter, r *http.Request, commentID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var p updateCommentPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	content := strings.TrimSpace(p.Content)
+	if len(content) < 1 {
+		sendErrorResponse(w, "Comment cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if len(content) > 4000 {
+		sendErrorResponse(w, "Comment too long", http.StatusBadRequest)
+		return
+	}
+
+	var postID, authorID int64
+	var oldContent string
+	var locked bool
+	if err := db.QueryRow(`SELECT c.post_id, c.user_id, c.content, p.locked_at IS NOT NULL
+		FROM comments c LEFT JOIN posts p ON p.post_id = c.post_id
+		WHERE c.comment_id=? AND c.deleted_at IS NULL`, commentID).
+		Scan(&postID, &authorID, &oldContent, &locked); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !canModifyPost(sess.UserID, authorID) {
+		sendErrorResponse(w, "You can only edit your own comments", http.StatusForbidden)
+		return
+	}
+	if locked && !isModerator(sess.UserID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
+
+	var added []mentionTarget
+	if content != oldContent {
+		if added, err = rewriteComment(commentID, postID, authorID, content); err != nil {
+			sendErrorResponse(w, "DB error ("+err.Error()+")", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	c, err := loadComment(commentID, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load comment)", http.StatusInternalServerError)
+		return
+	}
+
+	if content != oldContent {
+		Emit("comment.updated", map[string]any{
+			"comment_id":   commentID,
+			"post_id":      postID,
+			"content":      c.Content,
+			"content_html": c.ContentHTML,
+			"edited_at":    c.EditedAt.UTC().Format(time.RFC3339),
+		})
+		emitMentions(added, authorID, c.Username, postID, commentID, content)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Comment updated",
+		"data":    c,
+	})
+}
+
+// rewriteComment saves new content and re-records its mentions in one
+// transaction, returning the newly mentioned users. Errors name the failed
+// step.
+func rewriteComment(commentID, postID, authorID int64, content string) ([]mentionTarget, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, errors.New("begin tx")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE comments SET content=?, edited_at=CURRENT_TIMESTAMP WHERE comment_id=?`,
+		content, commentID); err != nil {
+		return nil, errors.New("update comment")
+	}
+	added, err := replaceMentions(tx, authorID, postID, commentID, content)
+	if err != nil {
+		return nil, errors.New("mentions")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.New("commit")
+	}
+	return added, nil
+}
+
+// DELETE /api/comments/{id} (author and moderators)
+//
+// A comment with replies becomes a "[deleted]" tombstone so the thread keeps
+// its shape; one without is removed, along with any tombstoned ancestors it
+// was the last reply to.
+func handleDeleteComment(w http.ResponseWriter, r *http.Request, commentID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var postID, authorID int64
+	var parentID sql.NullInt64
+	var locked bool
+	if err := db.QueryRow(`SELECT c.post_id, c.user_id, c.parent_id, p.locked_at IS NOT NULL
+		FROM comments c LEFT JOIN posts p ON p.post_id = c.post_id
+		WHERE c.comment_id=? AND c.deleted_at IS NULL`, commentID).
+		Scan(&postID, &authorID, &parentID, &locked); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Comment not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !canModifyPost(sess.UserID, authorID) {
+		sendErrorResponse(w, "You can only delete your own comments", http.StatusForbidden)
+		return
+	}
+	if locked && !isModerator(sess.UserID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range commentCleanup {
+		if _, err := tx.Exec(stmt, commentID); err != nil {
+			sendErrorResponse(w, "DB error (delete comment data)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var replies int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM comments WHERE parent_id=?`, commentID).Scan(&replies); err != nil {
+		sendErrorResponse(w, "DB error (count replies)", http.StatusInternalServerError)
+		return
+	}
+	tombstone := replies > 0
+
+	type removedComment struct {
+		id       int64
+		parentID *int64
+	}
+	var removed []removedComment
+	if tombstone {
+		_, err = tx.Exec(`UPDATE comments SET content='', deleted_at=CURRENT_TIMESTAMP WHERE comment_id=?`, commentID)
+	} else {
+		_, err = tx.Exec(`DELETE FROM comments WHERE comment_id=?`, commentID)
+		removed = append(removed, removedComment{commentID, nullableID(parentID)})
+
+		// Walk up through tombstones left without replies.
+		for err == nil && parentID.Valid {
+			id := parentID.Int64
+			var pruned bool
+			err = tx.QueryRow(`SELECT parent_id, deleted_at IS NOT NULL
+				AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.comment_id)
+				FROM comments c WHERE comment_id=?`, id).Scan(&parentID, &pruned)
+			if err == sql.ErrNoRows {
+				err = nil
+				break
+			}
+			if err != nil || !pruned {
+				break
+			}
+			if _, err = tx.Exec(`DELETE FROM comments WHERE comment_id=?`, id); err == nil {
+				removed = append(removed, removedComment{id, nullableID(parentID)})
+			}
+		}
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (delete comment)", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	if tombstone {
+		Emit("comment.deleted", map[string]any{
+			"comment_id":   commentID,
+			"post_id":      postID,
+			"parent_id":    nullableID(parentID),
+			"tombstone":    true,
+			"content":      deletedCommentText,
+			"content_html": markdown.Render(deletedCommentText),
+		})
+	}
+	for _, c := range removed {
+		Emit("comment.deleted", map[string]any{
+			"comment_id": c.id,
+			"post_id":    postID,
+			"parent_id":  c.parentID,
+			"tombstone":  false,
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"message":    "Comment deleted",
+		"comment_id": commentID,
+		"tombstone":  tombstone,
+	})
+}
+
+func nullableID(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	id := v.Int64
+	return &id
+}
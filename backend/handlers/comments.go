@@ -3,28 +3,62 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"realtimeforum/backend/markdown"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type commentDTO struct {
-	CommentID  int64     `json:"comment_id"`
-	PostID     int64     `json:"post_id"`
-	UserID     int64     `json:"user_id"`
-	Username   string    `json:"username"`
-	Content    string    `json:"content"`
-	CreatedAt  time.Time `json:"created_at"`
-	Likes      int       `json:"likes"`
-	Dislikes   int       `json:"dislikes"`
-	MyReaction string    `json:"my_reaction,omitempty"`
+	CommentID   int64        `json:"comment_id"`
+	PostID      int64        `json:"post_id"`
+	UserID      int64        `json:"user_id"`
+	Username    string       `json:"username"`
+	Content     string       `json:"content"`
+	ContentHTML string       `json:"content_html"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Likes       int          `json:"likes"`
+	Dislikes    int          `json:"dislikes"`
+	MyReaction  string       `json:"my_reaction,omitempty"`
+	EditedAt    *time.Time   `json:"edited_at,omitempty"`
+	Deleted     bool         `json:"deleted"`
+	ParentID    *int64       `json:"parent_id"`
+	ReplyCount  int          `json:"reply_count"`
+	Replies     []commentDTO `json:"replies,omitempty"`
 }
 
 type createCommentPayload struct {
-	Content string `json:"content"`
+	Content  string `json:"content"`
+	ParentID *int64 `json:"parent_id"`
 }
 
+// Listings embed replies this many levels deep (depth=) and show the newest
+// few replies of each comment (replies=); the rest load with parent_id=.
+const (
+	defaultReplyDepth   = 1
+	maxReplyDepth       = 3
+	defaultRepliesShown = 3
+	maxRepliesShown     = 10
+)
+
+// commentSelect loads a commentDTO; the first argument is the session user id
+// (0 when logged out) for my_reaction.
+const commentSelect = `
+SELECT c.comment_id, c.post_id, c.user_id, u.username, c.content, c.created_at,
+COALESCE(SUM(CASE WHEN r.type='like' THEN 1 END),0) AS likes,
+COALESCE(SUM(CASE WHEN r.type='dislike' THEN 1 END),0) AS dislikes,
+ur.type AS my_reaction,
+c.edited_at, c.deleted_at IS NOT NULL,
+c.parent_id,
+(SELECT COUNT(*) FROM comments rc WHERE rc.parent_id = c.comment_id) AS reply_count
+FROM comments c
+JOIN users u ON u.user_id = c.user_id
+LEFT JOIN reactions r ON r.comment_id = c.comment_id
+LEFT JOIN reactions ur ON ur.comment_id = c.comment_id AND ur.user_id = ?
+`
+
 type reactPayload struct {
 	Type string `json:"type"`
 }
@@ -43,10 +77,22 @@ func PostSubresourceRouter(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(parts) == 1 {
-		if r.Method == http.MethodGet || r.Method == http.MethodPost {
+		if r.Method == http.MethodGet {
+			handleGetPost(w, r, postID)
+			return
+		}
+		if r.Method == http.MethodPost {
 			PostsHandler(w, r)
 			return
 		}
+		if r.Method == http.MethodPut || r.Method == http.MethodPatch {
+			handleUpdatePost(w, r, postID)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			handleDeletePost(w, r, postID)
+			return
+		}
 		sendErrorResponse(w, "Not found", http.StatusNotFound)
 		return
 	}
@@ -67,6 +113,22 @@ func PostSubresourceRouter(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		handleReactPost(w, r, postID)
+	case "revisions":
+		if r.Method != http.MethodGet {
+			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handleListPostRevisions(w, r, postID)
+	case "poll":
+		sub := ""
+		if len(parts) > 2 {
+			sub = parts[2]
+		}
+		handlePoll(w, r, postID, sub)
+	case "bookmark":
+		handleBookmark(w, r, postID)
+	case "pin", "lock":
+		handlePostFlag(w, r, postID, parts[1])
 	default:
 		sendErrorResponse(w, "Not found", http.StatusNotFound)
 	}
@@ -94,8 +156,9 @@ func handleCreateComment(w http.ResponseWriter, r *http.Request, postID int64) {
 		return
 	}
 
-	var ok int
-	if err := db.QueryRow(`SELECT 1 FROM posts WHERE post_id=?`, postID).Scan(&ok); err != nil {
+	var postAuthorID int64
+	var locked bool
+	if err := db.QueryRow(`SELECT user_id, locked_at IS NOT NULL FROM posts WHERE post_id=?`, postID).Scan(&postAuthorID, &locked); err != nil {
 		if err == sql.ErrNoRows {
 			sendErrorResponse(w, "Post not found", http.StatusNotFound)
 			return
@@ -103,15 +166,61 @@ func handleCreateComment(w http.ResponseWriter, r *http.Request, postID int64) {
 		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
 		return
 	}
+	if locked && !isModerator(sess.UserID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
 
-	res, err := db.Exec(`INSERT INTO comments (post_id, user_id, content, created_at) VALUES (?,?,?, CURRENT_TIMESTAMP)`,
-		postID, sess.UserID, content)
+	var parentAuthorID int64
+	if p.ParentID != nil {
+		var parentPostID int64
+		var parentDeleted bool
+		err := db.QueryRow(`SELECT post_id, user_id, deleted_at IS NOT NULL FROM comments WHERE comment_id=?`, *p.ParentID).
+			Scan(&parentPostID, &parentAuthorID, &parentDeleted)
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		if parentPostID != postID {
+			sendErrorResponse(w, "Parent comment belongs to another post", http.StatusBadRequest)
+			return
+		}
+		if parentDeleted {
+			sendErrorResponse(w, "Cannot reply to a deleted comment", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO comments (post_id, user_id, content, created_at, parent_id) VALUES (?,?,?, CURRENT_TIMESTAMP, ?)`,
+		postID, sess.UserID, content, p.ParentID)
 	if err != nil {
 		sendErrorResponse(w, "DB error (insert comment)", http.StatusInternalServerError)
 		return
 	}
 	id, _ := res.LastInsertId()
 
+	mentioned, err := recordMentions(tx, sess.UserID, postID, id, content)
+	if err != nil {
+		sendErrorResponse(w, "DB error (mentions)", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
 	var username string
 	_ = db.QueryRow(`SELECT username FROM users WHERE user_id=?`, sess.UserID).Scan(&username)
 
@@ -121,13 +230,37 @@ func handleCreateComment(w http.ResponseWriter, r *http.Request, postID int64) {
 	}
 
 	Emit("comment.created", map[string]any{
-		"comment_id": id,
-		"post_id":    postID,
-		"user_id":    sess.UserID,
-		"username":   username,
-		"content":    content,
-		"created_at": createdAt.UTC().Format(time.RFC3339),
+		"comment_id":   id,
+		"post_id":      postID,
+		"user_id":      sess.UserID,
+		"username":     username,
+		"content":      content,
+		"content_html": markdown.Render(content),
+		"created_at":   createdAt.UTC().Format(time.RFC3339),
+		"parent_id":    p.ParentID,
 	})
+	emitMentions(mentioned, sess.UserID, username, postID, id, content)
+	if p.ParentID != nil && !mentionsUser(mentioned, parentAuthorID) {
+		notify(notification{
+			UserID:    parentAuthorID,
+			ActorID:   sess.UserID,
+			Kind:      notifyReply,
+			PostID:    postID,
+			CommentID: id,
+			Excerpt:   content,
+		})
+	}
+	// A post author replied to gets the reply notification only.
+	if !mentionsUser(mentioned, postAuthorID) && (p.ParentID == nil || parentAuthorID != postAuthorID) {
+		notify(notification{
+			UserID:    postAuthorID,
+			ActorID:   sess.UserID,
+			Kind:      notifyComment,
+			PostID:    postID,
+			CommentID: id,
+			Excerpt:   content,
+		})
+	}
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"success":    true,
@@ -136,6 +269,11 @@ func handleCreateComment(w http.ResponseWriter, r *http.Request, postID int64) {
 	})
 }
 
+// GET /api/posts/{id}/comments?before_id=&limit=&parent_id=&depth=&replies=
+//
+// Lists top-level comments, or the replies to parent_id, newest page first
+// and oldest first within the page. Each comment carries reply_count and up
+// to replies= of its newest replies, nested depth= levels deep (0 for none).
 func handleListComments(w http.ResponseWriter, r *http.Request, postID int64) {
 	var userID int64 = 0
 	if sess, err := GetSession(r); err == nil {
@@ -148,62 +286,49 @@ func handleListComments(w http.ResponseWriter, r *http.Request, postID int64) {
 		limit = 10
 	}
 	beforeID := toInt64(q.Get("before_id"), 0)
-
-	var rows *sql.Rows
-	var err error
-	if beforeID > 0 {
-		rows, err = db.Query(`
-SELECT c.comment_id, c.post_id, c.user_id, u.username, c.content, c.created_at,
-COALESCE(SUM(CASE WHEN r.type='like' THEN 1 END),0) AS likes,
-COALESCE(SUM(CASE WHEN r.type='dislike' THEN 1 END),0) AS dislikes,
-ur.type AS my_reaction
-FROM comments c
-JOIN users u ON u.user_id = c.user_id
-LEFT JOIN reactions r ON r.comment_id = c.comment_id
-LEFT JOIN reactions ur ON ur.comment_id = c.comment_id AND ur.user_id = ?
-WHERE c.post_id = ? AND c.comment_id < ?
-GROUP BY c.comment_id
-ORDER BY c.comment_id DESC
-LIMIT ?`, userID, postID, beforeID, limit)
+	parentID := toInt64(q.Get("parent_id"), 0)
+	depth := clamp(toInt(q.Get("depth"), defaultReplyDepth), 0, maxReplyDepth)
+	shown := clamp(toInt(q.Get("replies"), defaultRepliesShown), 1, maxRepliesShown)
+
+	query := commentSelect + `WHERE c.post_id = ?`
+	args := []any{userID, postID}
+	if parentID > 0 {
+		query += ` AND c.parent_id = ?`
+		args = append(args, parentID)
 	} else {
-		rows, err = db.Query(`
-SELECT c.comment_id, c.post_id, c.user_id, u.username, c.content, c.created_at,
-COALESCE(SUM(CASE WHEN r.type='like' THEN 1 END),0) AS likes,
-COALESCE(SUM(CASE WHEN r.type='dislike' THEN 1 END),0) AS dislikes,
-ur.type AS my_reaction
-FROM comments c
-JOIN users u ON u.user_id = c.user_id
-LEFT JOIN reactions r ON r.comment_id = c.comment_id
-LEFT JOIN reactions ur ON ur.comment_id = c.comment_id AND ur.user_id = ?
-WHERE c.post_id = ?
+		query += ` AND c.parent_id IS NULL`
+	}
+	if beforeID > 0 {
+		query += ` AND c.comment_id < ?`
+		args = append(args, beforeID)
+	}
+	query += `
 GROUP BY c.comment_id
 ORDER BY c.comment_id DESC
-LIMIT ?`, userID, postID, limit)
-	}
+LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		sendErrorResponse(w, "DB error (list comments)", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var items []commentDTO
-	for rows.Next() {
-		var c commentDTO
-		var myReaction sql.NullString
-		if err := rows.Scan(&c.CommentID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt, &c.Likes, &c.Dislikes, &myReaction); err != nil {
-			sendErrorResponse(w, "DB error (scan comment)", http.StatusInternalServerError)
-			return
-		}
-		if myReaction.Valid {
-			c.MyReaction = myReaction.String
-		}
-		items = append(items, c)
+	items, err := scanComments(rows)
+	rows.Close()
+	if err != nil {
+		sendErrorResponse(w, "DB error (scan comment)", http.StatusInternalServerError)
+		return
 	}
 
 	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
 		items[i], items[j] = items[j], items[i]
 	}
 
+	if err := attachReplies(items, userID, depth, shown); err != nil {
+		sendErrorResponse(w, "DB error (load replies)", http.StatusInternalServerError)
+		return
+	}
+
 	var nextBefore int64 = 0
 	if len(items) > 0 {
 		nextBefore = items[0].CommentID
@@ -217,6 +342,84 @@ LIMIT ?`, userID, postID, limit)
 	})
 }
 
+func scanComments(rows *sql.Rows) ([]commentDTO, error) {
+	var items []commentDTO
+	for rows.Next() {
+		var c commentDTO
+		var myReaction sql.NullString
+		var parentID sql.NullInt64
+		var editedAt sql.NullTime
+		if err := rows.Scan(&c.CommentID, &c.PostID, &c.UserID, &c.Username, &c.Content, &c.CreatedAt,
+			&c.Likes, &c.Dislikes, &myReaction, &editedAt, &c.Deleted, &parentID, &c.ReplyCount); err != nil {
+			return nil, err
+		}
+		if c.Deleted {
+			// Tombstones keep their place in the thread but not their author.
+			c.UserID, c.Username, c.Content = 0, "", deletedCommentText
+		}
+		c.ContentHTML = markdown.Render(c.Content)
+		if editedAt.Valid {
+			c.EditedAt = &editedAt.Time
+		}
+		if myReaction.Valid {
+			c.MyReaction = myReaction.String
+		}
+		if parentID.Valid {
+			c.ParentID = &parentID.Int64
+		}
+		items = append(items, c)
+	}
+	return items, rows.Err()
+}
+
+// attachReplies fills Replies with the newest shown replies of each comment,
+// oldest first, and recurses depth levels down. One query per level.
+func attachReplies(items []commentDTO, userID int64, depth, shown int) error {
+	if depth <= 0 {
+		return nil
+	}
+	args := []any{userID}
+	for _, c := range items {
+		if c.ReplyCount > 0 {
+			args = append(args, c.CommentID)
+		}
+	}
+	if len(args) == 1 {
+		return nil
+	}
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(args)-1), ",")
+	args = append(args, shown)
+
+	rows, err := db.Query(commentSelect+fmt.Sprintf(`WHERE c.comment_id IN (
+	SELECT comment_id FROM (
+		SELECT comment_id, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY comment_id DESC) AS n
+		FROM comments WHERE parent_id IN (%s)
+	) WHERE n <= ?)
+GROUP BY c.comment_id
+ORDER BY c.comment_id ASC`, placeholders), args...)
+	if err != nil {
+		return err
+	}
+	replies, err := scanComments(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := attachReplies(replies, userID, depth-1, shown); err != nil {
+		return err
+	}
+
+	byParent := make(map[int64][]commentDTO)
+	for _, c := range replies {
+		byParent[*c.ParentID] = append(byParent[*c.ParentID], c)
+	}
+	for i := range items {
+		items[i].Replies = byParent[items[i].CommentID]
+	}
+	return nil
+}
+
 func handleReactComment(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/comments/")
 	parts := strings.Split(path, "/")
@@ -258,8 +461,12 @@ func handleReactComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var postID int64
-	if err := db.QueryRow(`SELECT post_id FROM comments WHERE comment_id=?`, commentID).Scan(&postID); err != nil {
+	var postID, commentAuthorID int64
+	var commentContent string
+	var locked bool
+	if err := db.QueryRow(`SELECT c.post_id, c.user_id, c.content, p.locked_at IS NOT NULL
+		FROM comments c LEFT JOIN posts p ON p.post_id = c.post_id
+		WHERE c.comment_id=? AND c.deleted_at IS NULL`, commentID).Scan(&postID, &commentAuthorID, &commentContent, &locked); err != nil {
 		if err == sql.ErrNoRows {
 			sendErrorResponse(w, "Comment not found", http.StatusNotFound)
 			return
@@ -267,6 +474,10 @@ func handleReactComment(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
 		return
 	}
+	if locked && !isModerator(sess.UserID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
 
 	tx, err := db.Begin()
 	if err != nil {
@@ -326,6 +537,17 @@ FROM reactions WHERE comment_id=? AND post_id IS NULL`, commentID).Scan(&likes,
 		"likes":      likes,
 		"dislikes":   dislikes,
 	})
+	if t != "" {
+		notify(notification{
+			UserID:    commentAuthorID,
+			ActorID:   sess.UserID,
+			Kind:      notifyReaction,
+			PostID:    postID,
+			CommentID: commentID,
+			Detail:    t,
+			Excerpt:   commentContent,
+		})
+	}
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
@@ -337,13 +559,11 @@ FROM reactions WHERE comment_id=? AND post_id IS NULL`, commentID).Scan(&likes,
 	})
 }
 
+// /api/comments/{id}         GET, PUT/PATCH { "content": "..." }, DELETE
+// /api/comments/{id}/react   POST { "type": "like" | "dislike" }
 func CommentSubresourceRouter(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/comments/")
 	parts := strings.Split(path, "/")
-	if len(parts) < 2 {
-		sendErrorResponse(w, "Not found", http.StatusNotFound)
-		return
-	}
 
 	commentID, err := strconv.ParseInt(parts[0], 10, 64)
 	if err != nil || commentID <= 0 {
@@ -351,6 +571,20 @@ func CommentSubresourceRouter(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			handleGetComment(w, r, commentID)
+		case http.MethodPut, http.MethodPatch:
+			handleUpdateComment(w, r, commentID)
+		case http.MethodDelete:
+			handleDeleteComment(w, r, commentID)
+		default:
+			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
 	switch parts[1] {
 	case "react":
 		handleReactComment(w, r)
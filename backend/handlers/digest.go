@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"realtimeforum/backend/mailer"
+	"strings"
+	"time"
+)
+
+const maxDigestNotifications = 20
+
+type digestRecipient struct {
+	UserID   int64
+	Username string
+	Email    string
+	Since    string // same format as CURRENT_TIMESTAMP, compared as text
+}
+
+type digestSender struct {
+	Username string
+	Count    int
+}
+
+// RunDigestScheduler e-mails a digest every interval to opted-in users who
+// have been away for at least that long. It never returns.
+func RunDigestScheduler(m mailer.Mailer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sendDigests(m, interval)
+	}
+}
+
+func sendDigests(m mailer.Mailer, away time.Duration) {
+	if db == nil {
+		return
+	}
+
+	recipients, err := digestRecipients(away)
+	if err != nil {
+		log.Printf("digest: load recipients: %v", err)
+		return
+	}
+
+	for _, rcpt := range recipients {
+		body, ok, err := buildDigest(rcpt)
+		if err != nil {
+			log.Printf("digest: build for user %d: %v", rcpt.UserID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := m.Send(mailer.Message{
+			To:      rcpt.Email,
+			Subject: "What you missed on the forum",
+			Body:    body,
+		}); err != nil {
+			log.Printf("digest: send to user %d: %v", rcpt.UserID, err)
+			continue
+		}
+
+		if _, err := db.Exec(`UPDATE email_digests SET last_sent_at = CURRENT_TIMESTAMP WHERE user_id = ?`, rcpt.UserID); err != nil {
+			log.Printf("digest: mark sent for user %d: %v", rcpt.UserID, err)
+		}
+	}
+}
+
+// digestRecipients lists opted-in users last seen more than away ago. Since is
+// the later of their last visit and the previous digest, so nothing is sent twice.
+func digestRecipients(away time.Duration) ([]digestRecipient, error) {
+	rows, err := db.Query(`
+SELECT u.user_id, u.username, u.email,
+MAX(ua.last_seen, COALESCE(ds.last_sent_at, ua.last_seen)) AS since
+FROM email_digests ds
+JOIN users u ON u.user_id = ds.user_id
+JOIN user_activity ua ON ua.user_id = ds.user_id
+WHERE ds.enabled = TRUE AND ua.last_seen <= datetime('now', ?)`,
+		fmt.Sprintf("-%d seconds", int(away.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []digestRecipient
+	for rows.Next() {
+		var rcpt digestRecipient
+		if err := rows.Scan(&rcpt.UserID, &rcpt.Username, &rcpt.Email, &rcpt.Since); err != nil {
+			return nil, err
+		}
+		list = append(list, rcpt)
+	}
+	return list, rows.Err()
+}
+
+// buildDigest renders the digest for rcpt; ok is false when there is nothing new.
+func buildDigest(rcpt digestRecipient) (body string, ok bool, err error) {
+	senders, err := unreadMessageSenders(rcpt.UserID, rcpt.Since)
+	if err != nil {
+		return "", false, err
+	}
+
+	var total int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM notifications
+		WHERE user_id = ? AND is_read = FALSE AND kind != ? AND created_at > ?`,
+		rcpt.UserID, notifyMessage, rcpt.Since).Scan(&total); err != nil {
+		return "", false, err
+	}
+
+	if len(senders) == 0 && total == 0 {
+		return "", false, nil
+	}
+
+	rows, err := db.Query(notificationSelect+`WHERE n.user_id = ? AND n.is_read = FALSE AND n.kind != ? AND n.created_at > ?
+ORDER BY n.notification_id DESC
+LIMIT ?`, rcpt.UserID, notifyMessage, rcpt.Since, maxDigestNotifications)
+	if err != nil {
+		return "", false, err
+	}
+	items, err := scanNotifications(rows)
+	rows.Close()
+	if err != nil {
+		return "", false, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Hi %s,\n\nHere is what happened since your last visit.\n", rcpt.Username)
+
+	if len(senders) > 0 {
+		sb.WriteString("\nUnread messages:\n")
+		for _, s := range senders {
+			fmt.Fprintf(&sb, "  - %d from %s\n", s.Count, s.Username)
+		}
+	}
+
+	if total > 0 {
+		sb.WriteString("\nNotifications:\n")
+		for _, n := range items {
+			fmt.Fprintf(&sb, "  - %s\n", describeNotification(n))
+		}
+		if total > len(items) {
+			fmt.Fprintf(&sb, "  ... and %d more\n", total-len(items))
+		}
+	}
+
+	sb.WriteString("\nYou can turn these e-mails off in your preferences.\n")
+	return sb.String(), true, nil
+}
+
+// unreadMessageSenders groups unread DMs newer than since by sender, skipping muted conversations.
+func unreadMessageSenders(userID int64, since string) ([]digestSender, error) {
+	rows, err := db.Query(`
+SELECT u.username, COUNT(*)
+FROM private_messages pm
+JOIN users u ON u.user_id = pm.from_user_id
+WHERE pm.to_user_id = ? AND pm.is_read = FALSE AND pm.created_at > ?
+  AND pm.from_user_id NOT IN (SELECT other_user_id FROM muted_conversations WHERE user_id = ?)
+GROUP BY pm.from_user_id
+ORDER BY MAX(pm.id) DESC`, userID, since, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var senders []digestSender
+	for rows.Next() {
+		var s digestSender
+		if err := rows.Scan(&s.Username, &s.Count); err != nil {
+			return nil, err
+		}
+		senders = append(senders, s)
+	}
+	return senders, rows.Err()
+}
+
+func describeNotification(n notificationDTO) string {
+	switch n.Kind {
+	case notifyComment:
+		return fmt.Sprintf("%s commented on your post: %q", n.ActorUsername, n.Excerpt)
+	case notifyReply:
+		return fmt.Sprintf("%s replied to your comment: %q", n.ActorUsername, n.Excerpt)
+	case notifyReaction:
+		return fmt.Sprintf("%s reacted with a %s: %q", n.ActorUsername, n.Detail, n.Excerpt)
+	case notifyMention:
+		return fmt.Sprintf("%s mentioned you: %q", n.ActorUsername, n.Excerpt)
+	}
+	return fmt.Sprintf("%s: %q", n.ActorUsername, n.Excerpt)
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const draftPublishBatch = 50
+
+var errDraftNotDue = errors.New("draft is no longer scheduled")
+
+type draftDTO struct {
+	DraftID      int64      `json:"draft_id"`
+	Title        string     `json:"title"`
+	Content      string     `json:"content"`
+	Categories   []int64    `json:"categories"`
+	Tags         []string   `json:"tags"`
+	PublishAt    *time.Time `json:"publish_at,omitempty"`
+	PublishError string     `json:"publish_error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type draftPayload struct {
+	Title      *string   `json:"title"`
+	Content    *string   `json:"content"`
+	Categories *[]int64  `json:"categories"`
+	Tags       *[]string `json:"tags"`
+}
+
+const draftSelect = `
+SELECT draft_id, title, content, categories, tags, publish_at, publish_error, created_at, updated_at
+FROM post_drafts
+`
+
+func scanDraft(row interface{ Scan(...any) error }) (draftDTO, error) {
+	var d draftDTO
+	var cats, tags string
+	var publishAt sql.NullTime
+	var publishErr sql.NullString
+	if err := row.Scan(&d.DraftID, &d.Title, &d.Content, &cats, &tags, &publishAt, &publishErr, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal([]byte(cats), &d.Categories); err != nil || d.Categories == nil {
+		d.Categories = []int64{}
+	}
+	if err := json.Unmarshal([]byte(tags), &d.Tags); err != nil || d.Tags == nil {
+		d.Tags = []string{}
+	}
+	if publishAt.Valid {
+		d.PublishAt = &publishAt.Time
+	}
+	d.PublishError = publishErr.String
+	return d, nil
+}
+
+func loadDraft(draftID, userID int64) (draftDTO, error) {
+	return scanDraft(db.QueryRow(draftSelect+`WHERE draft_id = ? AND user_id = ?`, draftID, userID))
+}
+
+// GET /api/drafts -> the session user's drafts, most recently edited first
+// POST /api/drafts { "title": "...", "content": "...", "categories": [1, 2], "tags": ["go"] }
+func DraftsHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rows, err := db.Query(draftSelect+`WHERE user_id = ? ORDER BY updated_at DESC, draft_id DESC`, sess.UserID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (list drafts)", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		items := []draftDTO{}
+		for rows.Next() {
+			d, err := scanDraft(rows)
+			if err != nil {
+				sendErrorResponse(w, "DB error (scan draft)", http.StatusInternalServerError)
+				return
+			}
+			items = append(items, d)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"data":    items,
+		})
+
+	case http.MethodPost:
+		var p draftPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		title, content, cats := "", "", []int64{}
+		if p.Title != nil {
+			title = *p.Title
+		}
+		if p.Content != nil {
+			content = *p.Content
+		}
+		if p.Categories != nil {
+			cats = *p.Categories
+		}
+		catsJSON, _ := json.Marshal(cats)
+		tags := []string{}
+		if p.Tags != nil {
+			if tags, err = normalizeTags(*p.Tags); err != nil {
+				sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		tagsJSON, _ := json.Marshal(tags)
+
+		res, err := db.Exec(`INSERT INTO post_drafts (user_id, title, content, categories, tags) VALUES (?, ?, ?, ?, ?)`,
+			sess.UserID, title, content, string(catsJSON), string(tagsJSON))
+		if err != nil {
+			sendErrorResponse(w, "DB error (create draft)", http.StatusInternalServerError)
+			return
+		}
+		draftID, _ := res.LastInsertId()
+
+		d, err := loadDraft(draftID, sess.UserID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (load draft)", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"data":    d,
+		})
+
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// /api/drafts/{id}            GET, PUT/PATCH (autosave), DELETE
+// /api/drafts/{id}/schedule   POST { "publish_at": RFC 3339 } schedules, DELETE unschedules
+// /api/drafts/{id}/publish    POST publishes now
+func DraftSubresourceRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/drafts/")
+	parts := strings.Split(path, "/")
+	if len(parts) == 0 || parts[0] == "" {
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	draftID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || draftID <= 0 {
+		sendErrorResponse(w, "Invalid draft id", http.StatusBadRequest)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	d, err := loadDraft(draftID, sess.UserID)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Draft not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (load draft)", http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "data": d})
+		case http.MethodPut, http.MethodPatch:
+			handleUpdateDraft(w, r, d, sess.UserID)
+		case http.MethodDelete:
+			if _, err := db.Exec(`DELETE FROM post_drafts WHERE draft_id = ?`, draftID); err != nil {
+				sendErrorResponse(w, "DB error (delete draft)", http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"success": true, "draft_id": draftID})
+		default:
+			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	switch parts[1] {
+	case "schedule":
+		handleScheduleDraft(w, r, d, sess.UserID)
+	case "publish":
+		if r.Method != http.MethodPost {
+			sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := validatePost(strings.TrimSpace(d.Title), strings.TrimSpace(d.Content), d.Categories); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		postID, err := publishDraft(d.DraftID, false)
+		if err != nil {
+			sendErrorResponse(w, "DB error (publish draft: "+err.Error()+")", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"message": "Post created",
+			"post_id": postID,
+		})
+	default:
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// PUT replaces title, content and categories; PATCH changes only the fields present.
+func handleUpdateDraft(w http.ResponseWriter, r *http.Request, d draftDTO, userID int64) {
+	var p draftPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut && (p.Title == nil || p.Content == nil || p.Categories == nil) {
+		sendErrorResponse(w, "PUT requires title, content and categories", http.StatusBadRequest)
+		return
+	}
+
+	if p.Title != nil {
+		d.Title = *p.Title
+	}
+	if p.Content != nil {
+		d.Content = *p.Content
+	}
+	if p.Categories != nil {
+		d.Categories = *p.Categories
+	}
+	if p.Tags != nil {
+		tags, err := normalizeTags(*p.Tags)
+		if err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.Tags = tags
+	}
+	catsJSON, _ := json.Marshal(d.Categories)
+	tagsJSON, _ := json.Marshal(d.Tags)
+
+	if _, err := db.Exec(`UPDATE post_drafts SET title = ?, content = ?, categories = ?, tags = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE draft_id = ?`, d.Title, d.Content, string(catsJSON), string(tagsJSON), d.DraftID); err != nil {
+		sendErrorResponse(w, "DB error (update draft)", http.StatusInternalServerError)
+		return
+	}
+
+	d, err := loadDraft(d.DraftID, userID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load draft)", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "data": d})
+}
+
+func handleScheduleDraft(w http.ResponseWriter, r *http.Request, d draftDTO, userID int64) {
+	switch r.Method {
+	case http.MethodPost:
+		var p struct {
+			PublishAt string `json:"publish_at"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		at, err := time.Parse(time.RFC3339, p.PublishAt)
+		if err != nil {
+			sendErrorResponse(w, "publish_at must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		if !at.After(time.Now()) {
+			sendErrorResponse(w, "publish_at must be in the future", http.StatusBadRequest)
+			return
+		}
+		if err := validatePost(strings.TrimSpace(d.Title), strings.TrimSpace(d.Content), d.Categories); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err = db.Exec(`UPDATE post_drafts SET publish_at = ?, publish_error = NULL WHERE draft_id = ?`,
+			at.UTC().Format(sqliteTimeLayout), d.DraftID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (schedule draft)", http.StatusInternalServerError)
+			return
+		}
+	case http.MethodDelete:
+		if _, err := db.Exec(`UPDATE post_drafts SET publish_at = NULL WHERE draft_id = ?`, d.DraftID); err != nil {
+			sendErrorResponse(w, "DB error (unschedule draft)", http.StatusInternalServerError)
+			return
+		}
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	d, err := loadDraft(d.DraftID, userID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load draft)", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "data": d})
+}
+
+// publishDraft turns a draft into a post through insertPost, removes the
+// draft and announces the post. With scheduled set it only publishes a draft
+// that is still scheduled and due, so an unschedule or a second publisher
+// racing it wins cleanly (errDraftNotDue).
+func publishDraft(draftID int64, scheduled bool) (int64, error) {
+	var np newPost
+	var cats, tags, username string
+	if err := db.QueryRow(`
+SELECT d.user_id, u.username, d.title, d.content, d.categories, d.tags
+FROM post_drafts d
+JOIN users u ON u.user_id = d.user_id
+WHERE d.draft_id = ?`, draftID).Scan(&np.UserID, &username, &np.Title, &np.Content, &cats, &tags); err != nil {
+		return 0, err
+	}
+	np.Title = strings.TrimSpace(np.Title)
+	np.Content = strings.TrimSpace(np.Content)
+	json.Unmarshal([]byte(cats), &np.Categories)
+	json.Unmarshal([]byte(tags), &np.Tags)
+	if err := validatePost(np.Title, np.Content, np.Categories); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	postID, mentioned, err := insertPost(tx, np)
+	if err != nil {
+		return 0, err
+	}
+
+	del := `DELETE FROM post_drafts WHERE draft_id = ?`
+	if scheduled {
+		del += ` AND publish_at IS NOT NULL AND publish_at <= datetime('now')`
+	}
+	res, err := tx.Exec(del, draftID)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return 0, errDraftNotDue
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	announcePost(postID, np, username, mentioned)
+	return postID, nil
+}
+
+// RunDraftPublisher publishes scheduled drafts whose time has come, checking
+// every interval. It never returns.
+func RunDraftPublisher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		publishDueDrafts()
+	}
+}
+
+func publishDueDrafts() {
+	if db == nil {
+		return
+	}
+
+	due, err := loadIDs(`SELECT draft_id FROM post_drafts
+		WHERE publish_at IS NOT NULL AND publish_at <= datetime('now')
+		ORDER BY publish_at, draft_id
+		LIMIT ?`, draftPublishBatch)
+	if err != nil {
+		log.Printf("drafts: load due drafts: %v", err)
+		return
+	}
+
+	for _, draftID := range due {
+		postID, err := publishDraft(draftID, true)
+		switch {
+		case err == nil:
+			log.Printf("drafts: published draft %d as post %d", draftID, postID)
+		case err == errDraftNotDue || err == sql.ErrNoRows:
+		default:
+			// Unschedule so a draft that cannot be published is not retried
+			// forever; the author sees why in publish_error.
+			log.Printf("drafts: publish draft %d: %v", draftID, err)
+			db.Exec(`UPDATE post_drafts SET publish_at = NULL, publish_error = ? WHERE draft_id = ?`, err.Error(), draftID)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// followedPostClause matches posts filed under a category the user (the
+// clause's two arguments) follows and under none they muted.
+const followedPostClause = `EXISTS (
+	SELECT 1 FROM post_categories fpc
+	JOIN category_follows cf ON cf.category_id = fpc.category_id
+	WHERE fpc.post_id = p.post_id AND cf.user_id = ?)
+AND NOT EXISTS (
+	SELECT 1 FROM post_categories mpc
+	JOIN muted_categories mc ON mc.category_id = mpc.category_id
+	WHERE mpc.post_id = p.post_id AND mc.user_id = ?)`
+
+// /api/categories/{id}/follow   PUT follows, DELETE unfollows
+func handleFollowCategory(w http.ResponseWriter, r *http.Request, c categoryDetailDTO) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		if c.Archived {
+			sendErrorResponse(w, "Archived categories cannot be followed", http.StatusBadRequest)
+			return
+		}
+		_, err = db.Exec(`INSERT OR IGNORE INTO category_follows (user_id, category_id) VALUES (?, ?)`, sess.UserID, c.ID)
+	case http.MethodDelete:
+		_, err = db.Exec(`DELETE FROM category_follows WHERE user_id = ? AND category_id = ?`, sess.UserID, c.ID)
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (follow category)", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"id":       c.ID,
+		"followed": r.Method != http.MethodDelete,
+	})
+}
+
+// postFollowers maps each user following one of the post's categories to
+// the followed categories it is filed under. The author and users who muted
+// any of its categories are left out.
+func postFollowers(postID, authorID int64) map[int64][]int64 {
+	rows, err := db.Query(`
+SELECT cf.user_id, cf.category_id FROM category_follows cf
+JOIN post_categories pc ON pc.category_id = cf.category_id
+WHERE pc.post_id = ?1 AND cf.user_id != ?2
+AND NOT EXISTS (
+	SELECT 1 FROM post_categories mpc
+	JOIN muted_categories mc ON mc.category_id = mpc.category_id
+	WHERE mpc.post_id = ?1 AND mc.user_id = cf.user_id)
+ORDER BY cf.user_id, cf.category_id`, postID, authorID)
+	if err != nil {
+		log.Printf("follows: load followers of post %d: %v", postID, err)
+		return nil
+	}
+	defer rows.Close()
+
+	followers := make(map[int64][]int64)
+	for rows.Next() {
+		var uid, cid int64
+		if err := rows.Scan(&uid, &cid); err != nil {
+			log.Printf("follows: scan follower of post %d: %v", postID, err)
+			return nil
+		}
+		followers[uid] = append(followers[uid], cid)
+	}
+	return followers
+}
+
+// GET /api/feed?before_id=&limit= -> newest posts from the categories the
+// session user follows. Accepts the /api/posts filters (author, tag, ...).
+// nextCursor is the before_id for the next page.
+func FeedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	beforeID := toInt64(q.Get("before_id"), 0)
+
+	var sbWhere strings.Builder
+	whereArgs := []any{sess.UserID, sess.UserID}
+	addWhere(&sbWhere, followedPostClause)
+	if err := addPostFilters(q, sess.UserID, &sbWhere, &whereArgs); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if beforeID > 0 {
+		addWhere(&sbWhere, `p.post_id < ?`)
+		whereArgs = append(whereArgs, beforeID)
+	}
+
+	query := postSelect + "WHERE " + sbWhere.String() + `
+ORDER BY p.post_id DESC
+LIMIT ?`
+	args := append([]any{sess.UserID}, whereArgs...)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendErrorResponse(w, "DB error (feed)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	posts := []postDTO{}
+	var postIDs []int64
+	var nextBefore int64
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			sendErrorResponse(w, "DB error (scan)", http.StatusInternalServerError)
+			return
+		}
+		posts = append(posts, p)
+		postIDs = append(postIDs, p.PostID)
+		nextBefore = p.PostID
+	}
+	if err := rows.Err(); err != nil {
+		sendErrorResponse(w, "DB error (feed)", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	if len(postIDs) > 0 {
+		if err := attachCategories(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load categories)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachTags(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load tags)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachPolls(posts, postIDs, sess.UserID); err != nil {
+			sendErrorResponse(w, "DB error (load polls)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachBookmarks(posts, postIDs, sess.UserID); err != nil {
+			sendErrorResponse(w, "DB error (load bookmarks)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"data":       posts,
+		"nextCursor": nextBefore,
+		"count":      len(posts),
+	})
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type forumCategoryDTO struct {
+	categoryDetailDTO
+	PostCount    int            `json:"post_count"`
+	CommentCount int            `json:"comment_count"`
+	LatestPost   *latestPostDTO `json:"latest_post"`
+	NewPosts     *int           `json:"new_posts,omitempty"`
+}
+
+type latestPostDTO struct {
+	PostID    int64     `json:"post_id"`
+	Title     string    `json:"title"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Totals come from category_stats and the latest post from the
+// (category_id, post_id) index, so the index costs one row lookup per
+// category. New posts are those after the reader's last visit to the
+// category, not their own. For a category they never opened, the cutoff is
+// the last post written before they registered, found once through the
+// created_at index, so both cases count along the (category_id, post_id)
+// index.
+const forumIndexQuery = `
+WITH joined AS (
+	SELECT COALESCE((
+		SELECT post_id FROM posts
+		WHERE created_at <= (SELECT created_at FROM users WHERE user_id = ?1)
+		ORDER BY created_at DESC, post_id DESC LIMIT 1), 0) AS last_post_id
+)
+SELECT c.category_id, c.name, c.description, c.position, c.archived_at IS NOT NULL,
+	COALESCE(s.posts, 0), COALESCE(s.comments, 0),
+	lp.post_id, lp.title, lp.user_id, lu.username, lp.created_at,
+	CASE WHEN ?1 = 0 THEN NULL ELSE (
+		SELECT COUNT(*) FROM post_categories npc JOIN posts np ON np.post_id = npc.post_id
+		WHERE npc.category_id = c.category_id AND npc.post_id > COALESCE(v.last_post_id, joined.last_post_id)
+		AND np.user_id != ?1)
+	END
+FROM categories c
+CROSS JOIN joined
+LEFT JOIN category_stats s ON s.category_id = c.category_id
+LEFT JOIN posts lp ON lp.post_id = (SELECT MAX(pc.post_id) FROM post_categories pc WHERE pc.category_id = c.category_id)
+LEFT JOIN users lu ON lu.user_id = lp.user_id
+LEFT JOIN category_visits v ON v.category_id = c.category_id AND v.user_id = ?1
+WHERE c.archived_at IS NULL
+ORDER BY c.position ASC, c.name ASC`
+
+// GET /api/forum -> every live category with its post and comment counts,
+// latest post and, for logged-in readers, new_posts since their last visit.
+func ForumIndexHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var userID int64
+	if sess, err := GetSession(r); err == nil {
+		userID = sess.UserID
+	}
+
+	if err := ensureDefaultCategories(); err != nil {
+		sendErrorResponse(w, "DB error (ensure categories)", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := db.Query(forumIndexQuery, userID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (forum index)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []forumCategoryDTO{}
+	for rows.Next() {
+		var c forumCategoryDTO
+		var latestID, latestUserID, newPosts sql.NullInt64
+		var latestTitle, latestUsername sql.NullString
+		var latestAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.Position, &c.Archived,
+			&c.PostCount, &c.CommentCount,
+			&latestID, &latestTitle, &latestUserID, &latestUsername, &latestAt,
+			&newPosts); err != nil {
+			sendErrorResponse(w, "DB error (scan category)", http.StatusInternalServerError)
+			return
+		}
+		if latestID.Valid {
+			c.LatestPost = &latestPostDTO{
+				PostID:    latestID.Int64,
+				Title:     latestTitle.String,
+				UserID:    latestUserID.Int64,
+				Username:  latestUsername.String,
+				CreatedAt: latestAt.Time,
+			}
+		}
+		if newPosts.Valid {
+			n := int(newPosts.Int64)
+			c.NewPosts = &n
+		}
+		items = append(items, c)
+	}
+	if err := rows.Err(); err != nil {
+		sendErrorResponse(w, "DB error (forum index)", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    items,
+	})
+}
+
+// markCategoryVisited records that userID has seen every post currently in
+// the category, resetting its new_posts count on the forum index.
+func markCategoryVisited(userID, categoryID int64) {
+	_, err := db.Exec(`
+INSERT INTO category_visits (user_id, category_id, last_post_id, visited_at)
+VALUES (?1, ?2, COALESCE((SELECT MAX(post_id) FROM post_categories WHERE category_id = ?2), 0), CURRENT_TIMESTAMP)
+ON CONFLICT(user_id, category_id) DO UPDATE SET last_post_id = excluded.last_post_id, visited_at = excluded.visited_at`,
+		userID, categoryID)
+	if err != nil {
+		log.Printf("forum: mark category %d visited for user %d: %v", categoryID, userID, err)
+	}
+}
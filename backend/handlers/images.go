@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	maxImageUploadSize = 5 << 20 // 5MB
+	maxImagePixels     = 25_000_000
+	thumbnailMaxSide   = 320
+
+	postImageDir       = "frontend/assets/uploads/posts"
+	postImageURLPrefix = "/assets/uploads/posts/"
+)
+
+// Image types we can decode with the standard library, and therefore thumbnail.
+var decodableImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+var imageExtensions = map[string]string{
+	"image/png":    "png",
+	"image/jpeg":   "jpg",
+	"image/gif":    "gif",
+	"image/webp":   "webp",
+	"image/bmp":    "bmp",
+	"image/x-icon": "ico",
+}
+
+// storeImageUpload checks size and sniffed content type, then copies the file
+// into dir under a generated name. allowed limits the accepted types; nil
+// accepts any image. It returns the public URL path and the filesystem path.
+func storeImageUpload(file multipart.File, header *multipart.FileHeader, dir, urlPrefix string, allowed map[string]bool) (string, string, error) {
+	if header.Size > maxImageUploadSize {
+		return "", "", fmt.Errorf("file too large: max size is 5MB")
+	}
+
+	buff := make([]byte, 512)
+	n, err := file.Read(buff)
+	if err != nil && err != io.EOF {
+		return "", "", err
+	}
+
+	filetype := http.DetectContentType(buff[:n])
+	if !strings.HasPrefix(filetype, "image/") || (allowed != nil && !allowed[filetype]) {
+		return "", "", fmt.Errorf("invalid file type: only images are allowed")
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+
+	ext, ok := imageExtensions[filetype]
+	if !ok {
+		ext = strings.TrimPrefix(strings.TrimPrefix(filetype, "image/"), "x-")
+	}
+	filename := fmt.Sprintf("%d_%s.%s", time.Now().UnixNano(), GenerateRandomString(8), ext)
+	fsPath := filepath.Join(dir, filename)
+
+	dst, err := os.Create(fsPath)
+	if err != nil {
+		return "", "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		os.Remove(fsPath)
+		return "", "", err
+	}
+
+	return urlPrefix + filename, fsPath, nil
+}
+
+// makeThumbnail writes a copy of srcPath scaled to fit thumbnailMaxSide next
+// to it and returns the thumbnail's filesystem path. JPEGs stay JPEG; PNG and
+// GIF sources become PNG so transparency survives.
+func makeThumbnail(srcPath string) (string, error) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return "", fmt.Errorf("image too large: max %d pixels", maxImagePixels)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+	thumb := downscale(src, thumbnailMaxSide)
+
+	base := strings.TrimSuffix(srcPath, filepath.Ext(srcPath)) + "_thumb"
+	if format == "jpeg" {
+		dstPath := base + ".jpg"
+		return dstPath, writeImage(dstPath, func(w io.Writer) error {
+			return jpeg.Encode(w, thumb, &jpeg.Options{Quality: 80})
+		})
+	}
+	dstPath := base + ".png"
+	return dstPath, writeImage(dstPath, func(w io.Writer) error {
+		return png.Encode(w, thumb)
+	})
+}
+
+func writeImage(path string, encode func(io.Writer) error) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(out); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
+
+// downscale shrinks src to fit in a maxSide square by averaging the
+// (premultiplied) source pixels covered by each destination pixel. Smaller
+// images are returned as is.
+func downscale(src image.Image, maxSide int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+
+	dw, dh := maxSide, maxSide
+	if w >= h {
+		dh = max(1, h*maxSide/w)
+	} else {
+		dw = max(1, w*maxSide/h)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.RGBA64Model.Convert(src.At(sx, sy)).(color.RGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// removeUpload deletes a file previously returned by storeImageUpload, given its URL path.
+func removeUpload(urlPath string) {
+	rel, ok := strings.CutPrefix(urlPath, "/assets/uploads/")
+	if !ok || strings.Contains(rel, "..") {
+		return
+	}
+	os.Remove(filepath.Join("frontend/assets/uploads", filepath.FromSlash(rel)))
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const maxMentionsPerItem = 20
+
+// Same character set as ValidateUsername, without the minimum length because the
+// seeded test users have one-letter names. The leading group keeps e-mail
+// addresses such as a@b.com from being read as mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-zA-Z0-9_@])@([a-zA-Z0-9_]{1,20})\b`)
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx.
+type sqlExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type mentionTarget struct {
+	MentionID int64
+	UserID    int64
+	Username  string
+}
+
+type mentionDTO struct {
+	MentionID      int64     `json:"mention_id"`
+	PostID         int64     `json:"post_id"`
+	CommentID      *int64    `json:"comment_id,omitempty"`
+	PostTitle      string    `json:"post_title"`
+	AuthorID       int64     `json:"author_id"`
+	AuthorUsername string    `json:"author_username"`
+	Excerpt        string    `json:"excerpt"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// parseMentions returns the distinct usernames referenced as @username in text.
+func parseMentions(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := m[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentionsPerItem {
+			break
+		}
+	}
+	return names
+}
+
+// recordMentions resolves the @usernames in text and stores a mention row for
+// each existing user other than the author. commentID is 0 for mentions in a post.
+func recordMentions(ex sqlExecutor, authorID, postID, commentID int64, text string) ([]mentionTarget, error) {
+	names := parseMentions(text)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(names)), ",")
+	args := make([]any, 0, len(names)+1)
+	for _, n := range names {
+		args = append(args, n)
+	}
+	args = append(args, authorID)
+
+	rows, err := ex.Query(fmt.Sprintf(`SELECT user_id, username FROM users WHERE username IN (%s) AND user_id != ?`, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	var targets []mentionTarget
+	for rows.Next() {
+		var t mentionTarget
+		if err := rows.Scan(&t.UserID, &t.Username); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var cid any
+	if commentID > 0 {
+		cid = commentID
+	}
+	for i := range targets {
+		res, err := ex.Exec(`INSERT INTO mentions (user_id, author_id, post_id, comment_id) VALUES (?, ?, ?, ?)`,
+			targets[i].UserID, authorID, postID, cid)
+		if err != nil {
+			return nil, err
+		}
+		targets[i].MentionID, _ = res.LastInsertId()
+	}
+	return targets, nil
+}
+
+// replaceMentions re-records the mentions of a post (commentID 0) or comment
+// after its text changed. Users no longer mentioned lose the mention and its
+// notification; the returned targets are the newly mentioned users, for the
+// caller to pass to emitMentions once tx is committed.
+func replaceMentions(tx *sql.Tx, authorID, postID, commentID int64, text string) ([]mentionTarget, error) {
+	rows, err := tx.Query(`SELECT user_id FROM mentions WHERE post_id = ? AND comment_id IS ?`, postID, nullID(commentID))
+	if err != nil {
+		return nil, err
+	}
+	wasMentioned := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		wasMentioned[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM mentions WHERE post_id = ? AND comment_id IS ?`, postID, nullID(commentID)); err != nil {
+		return nil, err
+	}
+	mentioned, err := recordMentions(tx, authorID, postID, commentID, text)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []mentionTarget
+	for _, t := range mentioned {
+		if !wasMentioned[t.UserID] {
+			added = append(added, t)
+		}
+		delete(wasMentioned, t.UserID)
+	}
+	for id := range wasMentioned {
+		if _, err := tx.Exec(`DELETE FROM notifications WHERE user_id = ? AND kind = ? AND post_id = ? AND comment_id IS ?`,
+			id, notifyMention, postID, nullID(commentID)); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// emitMentions pushes a realtime "mention" event to every mentioned user.
+func emitMentions(targets []mentionTarget, authorID int64, authorName string, postID, commentID int64, text string) {
+	for _, t := range targets {
+		data := map[string]any{
+			"mention_id":      t.MentionID,
+			"post_id":         postID,
+			"author_id":       authorID,
+			"author_username": authorName,
+			"excerpt":         excerpt(text, 140),
+			"created_at":      time.Now().UTC().Format(time.RFC3339),
+		}
+		if commentID > 0 {
+			data["comment_id"] = commentID
+		}
+		EmitToUser(int(t.UserID), "mention", data)
+		notify(notification{
+			UserID:    t.UserID,
+			ActorID:   authorID,
+			Kind:      notifyMention,
+			PostID:    postID,
+			CommentID: commentID,
+			Excerpt:   text,
+		})
+	}
+}
+
+func mentionsUser(targets []mentionTarget, userID int64) bool {
+	for _, t := range targets {
+		if t.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func excerpt(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
+// GET /api/mentions -> recent mentions of the session user
+func MentionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	beforeID := toInt64(q.Get("before_id"), 0)
+
+	query := `
+SELECT m.mention_id, m.post_id, m.comment_id, p.title, m.author_id, u.username,
+COALESCE(c.content, p.content) AS body, m.created_at
+FROM mentions m
+JOIN posts p ON p.post_id = m.post_id
+JOIN users u ON u.user_id = m.author_id
+LEFT JOIN comments c ON c.comment_id = m.comment_id
+WHERE m.user_id = ?`
+	args := []any{sess.UserID}
+	if beforeID > 0 {
+		query += ` AND m.mention_id < ?`
+		args = append(args, beforeID)
+	}
+	query += `
+ORDER BY m.mention_id DESC
+LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list mentions)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []mentionDTO{}
+	for rows.Next() {
+		var m mentionDTO
+		var commentID sql.NullInt64
+		var body string
+		if err := rows.Scan(&m.MentionID, &m.PostID, &commentID, &m.PostTitle, &m.AuthorID, &m.AuthorUsername, &body, &m.CreatedAt); err != nil {
+			sendErrorResponse(w, "DB error (scan mention)", http.StatusInternalServerError)
+			return
+		}
+		if commentID.Valid {
+			m.CommentID = &commentID.Int64
+		}
+		m.Excerpt = excerpt(body, 140)
+		items = append(items, m)
+	}
+
+	var nextBefore int64 = 0
+	if len(items) > 0 {
+		nextBefore = items[len(items)-1].MentionID
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"data":       items,
+		"nextCursor": nextBefore,
+		"count":      len(items),
+	})
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification kinds stored in notifications.kind
+const (
+	notifyComment  = "comment"  // someone commented on my post
+	notifyReply    = "reply"    // someone replied to my comment
+	notifyReaction = "reaction" // someone reacted to my post or comment
+	notifyMention  = "mention"  // someone @mentioned me
+	notifyMessage  = "message"  // someone sent me a private message
+)
+
+// notification describes an event to record for UserID. Zero ids are stored as NULL.
+type notification struct {
+	UserID    int64
+	ActorID   int64
+	Kind      string
+	PostID    int64
+	CommentID int64
+	MessageID int64
+	Detail    string
+	Excerpt   string
+}
+
+type notificationDTO struct {
+	NotificationID int64     `json:"notification_id"`
+	Kind           string    `json:"kind"`
+	ActorID        int64     `json:"actor_id"`
+	ActorUsername  string    `json:"actor_username"`
+	PostID         *int64    `json:"post_id,omitempty"`
+	CommentID      *int64    `json:"comment_id,omitempty"`
+	MessageID      *int64    `json:"message_id,omitempty"`
+	Detail         string    `json:"detail,omitempty"`
+	Excerpt        string    `json:"excerpt"`
+	IsRead         bool      `json:"is_read"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+const notificationSelect = `
+SELECT n.notification_id, n.kind, n.actor_id, u.username, n.post_id, n.comment_id, n.message_id,
+COALESCE(n.detail, ''), n.excerpt, n.is_read, n.created_at
+FROM notifications n
+JOIN users u ON u.user_id = n.actor_id
+`
+
+func nullID(id int64) any {
+	if id > 0 {
+		return id
+	}
+	return nil
+}
+
+// notify stores n and pushes a "notification" event to the recipient, subject to
+// the recipient's preferences (see liveEventAllowed). Must not be called while a
+// transaction is open: the pool has a single connection.
+func notify(n notification) {
+	if n.UserID <= 0 || n.UserID == n.ActorID {
+		return
+	}
+	if notificationDelivery(n.UserID, n.Kind) == deliveryOff {
+		return
+	}
+
+	// A reaction replaces the actor's earlier reaction notification on the
+	// same target, so toggling a like does not pile up entries.
+	if n.Kind == notifyReaction {
+		db.Exec(`DELETE FROM notifications
+			WHERE user_id = ? AND actor_id = ? AND kind = ? AND post_id IS ? AND comment_id IS ?`,
+			n.UserID, n.ActorID, n.Kind, nullID(n.PostID), nullID(n.CommentID))
+	}
+
+	res, err := db.Exec(`INSERT INTO notifications
+		(user_id, actor_id, kind, post_id, comment_id, message_id, detail, excerpt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.UserID, n.ActorID, n.Kind, nullID(n.PostID), nullID(n.CommentID), nullID(n.MessageID),
+		sql.NullString{String: n.Detail, Valid: n.Detail != ""}, excerpt(n.Excerpt, 140))
+	if err != nil {
+		log.Printf("notify: insert %s notification for user %d: %v", n.Kind, n.UserID, err)
+		return
+	}
+	id, _ := res.LastInsertId()
+
+	rows, err := db.Query(notificationSelect+`WHERE n.notification_id = ?`, id)
+	if err != nil {
+		return
+	}
+	items, err := scanNotifications(rows)
+	rows.Close()
+	if err != nil || len(items) == 0 {
+		return
+	}
+
+	EmitToUser(int(n.UserID), "notification", map[string]any{
+		"notification": items[0],
+		"unread_count": unreadNotificationCount(n.UserID),
+	})
+}
+
+func unreadNotificationCount(userID int64) int {
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = FALSE`, userID).Scan(&count)
+	return count
+}
+
+func scanNotifications(rows *sql.Rows) ([]notificationDTO, error) {
+	items := []notificationDTO{}
+	for rows.Next() {
+		var n notificationDTO
+		var postID, commentID, messageID sql.NullInt64
+		if err := rows.Scan(&n.NotificationID, &n.Kind, &n.ActorID, &n.ActorUsername,
+			&postID, &commentID, &messageID, &n.Detail, &n.Excerpt, &n.IsRead, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if postID.Valid {
+			n.PostID = &postID.Int64
+		}
+		if commentID.Valid {
+			n.CommentID = &commentID.Int64
+		}
+		if messageID.Valid {
+			n.MessageID = &messageID.Int64
+		}
+		items = append(items, n)
+	}
+	return items, rows.Err()
+}
+
+// GET /api/notifications?unread=true&limit=N&before_id=M
+func NotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	beforeID := toInt64(q.Get("before_id"), 0)
+
+	var sbWhere strings.Builder
+	args := []any{}
+	addWhere(&sbWhere, `n.user_id = ?`)
+	args = append(args, sess.UserID)
+	if q.Get("unread") == "true" {
+		addWhere(&sbWhere, `n.is_read = FALSE`)
+	}
+	if beforeID > 0 {
+		addWhere(&sbWhere, `n.notification_id < ?`)
+		args = append(args, beforeID)
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(notificationSelect+"WHERE "+sbWhere.String()+`
+ORDER BY n.notification_id DESC
+LIMIT ?`, args...)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list notifications)", http.StatusInternalServerError)
+		return
+	}
+	items, err := scanNotifications(rows)
+	rows.Close()
+	if err != nil {
+		sendErrorResponse(w, "DB error (scan notification)", http.StatusInternalServerError)
+		return
+	}
+
+	var nextBefore int64 = 0
+	if len(items) > 0 {
+		nextBefore = items[len(items)-1].NotificationID
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"data":         items,
+		"nextCursor":   nextBefore,
+		"count":        len(items),
+		"unread_count": unreadNotificationCount(sess.UserID),
+	})
+}
+
+// GET /api/notifications/unread-count -> badge count
+func UnreadNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"unread_count": unreadNotificationCount(sess.UserID),
+	})
+}
+
+// POST /api/notifications/read-all
+func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := db.Exec(`UPDATE notifications SET is_read = TRUE WHERE user_id = ? AND is_read = FALSE`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (mark notifications read)", http.StatusInternalServerError)
+		return
+	}
+	updated, _ := res.RowsAffected()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"updated":      updated,
+		"unread_count": 0,
+	})
+}
+
+// POST /api/notifications/{id}/read
+func NotificationSubresourceRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/notifications/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[1] != "read" {
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || notificationID <= 0 {
+		sendErrorResponse(w, "Invalid notification id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := db.Exec(`UPDATE notifications SET is_read = TRUE WHERE notification_id = ? AND user_id = ?`,
+		notificationID, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (mark notification read)", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		sendErrorResponse(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"unread_count": unreadNotificationCount(sess.UserID),
+	})
+}
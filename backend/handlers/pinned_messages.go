@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// /api/private-messages/{id}/star and /api/private-messages/{id}/pin
+// POST marks the message, DELETE clears the mark.
+func PrivateMessageSubresourceRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/private-messages/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	messageID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || messageID <= 0 {
+		sendErrorResponse(w, "Invalid message id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch parts[1] {
+	case "star":
+		handleStarMessage(w, r, messageID, r.Method == http.MethodPost)
+	case "pin":
+		handlePinMessage(w, r, messageID, r.Method == http.MethodPost)
+	default:
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+	}
+}
+
+// conversationPartner returns the other participant of the conversation the
+// message belongs to, or sql.ErrNoRows if userID is not part of it.
+func conversationPartner(messageID, userID int64) (int64, error) {
+	var fromID, toID int64
+	err := db.QueryRow(`SELECT from_user_id, to_user_id FROM private_messages WHERE id = ?`, messageID).
+		Scan(&fromID, &toID)
+	if err != nil {
+		return 0, err
+	}
+	switch userID {
+	case fromID:
+		return toID, nil
+	case toID:
+		return fromID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func handleStarMessage(w http.ResponseWriter, r *http.Request, messageID int64, starred bool) {
+	sess, err := GetSession(r)
+	if err != nil || sess == nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := conversationPartner(messageID, sess.UserID); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	if starred {
+		_, err = db.Exec(`INSERT OR IGNORE INTO starred_messages (user_id, message_id) VALUES (?, ?)`,
+			sess.UserID, messageID)
+	} else {
+		_, err = db.Exec(`DELETE FROM starred_messages WHERE user_id = ? AND message_id = ?`,
+			sess.UserID, messageID)
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (star message)", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"message_id": messageID,
+		"is_starred": starred,
+	})
+}
+
+func handlePinMessage(w http.ResponseWriter, r *http.Request, messageID int64, pinned bool) {
+	sess, err := GetSession(r)
+	if err != nil || sess == nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	otherID, err := conversationPartner(messageID, sess.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	eventType := "message_pinned"
+	if pinned {
+		_, err = db.Exec(`INSERT OR IGNORE INTO pinned_messages (message_id, pinned_by) VALUES (?, ?)`,
+			messageID, sess.UserID)
+	} else {
+		eventType = "message_unpinned"
+		_, err = db.Exec(`DELETE FROM pinned_messages WHERE message_id = ?`, messageID)
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (pin message)", http.StatusInternalServerError)
+		return
+	}
+
+	event := map[string]interface{}{
+		"message_id":   messageID,
+		"from_user_id": sess.UserID,
+		"username":     sess.Username,
+		"is_pinned":    pinned,
+	}
+	EmitToUser(int(otherID), eventType, event)
+	EmitToUser(int(sess.UserID), eventType, event)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"message_id": messageID,
+		"is_pinned":  pinned,
+	})
+}
+
+// GET /api/private-messages/starred -> the session user's starred messages across all conversations
+func GetStarredMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil || sess == nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	page := clamp(toInt(q.Get("page"), 1), 1, 1000000)
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	offset := (page - 1) * limit
+
+	rows, err := db.Query(`
+		SELECT
+			pm.id, pm.from_user_id, pm.to_user_id, pm.content,
+			pm.message_type, pm.is_read, pm.created_at,
+			u.username, u.profile_picture,
+			1 AS is_starred,
+			CASE WHEN pin.message_id IS NOT NULL THEN 1 ELSE 0 END AS is_pinned
+		FROM starred_messages sm
+		JOIN private_messages pm ON pm.id = sm.message_id
+		JOIN users u ON pm.from_user_id = u.user_id
+		LEFT JOIN pinned_messages pin ON pin.message_id = pm.id
+		WHERE sm.user_id = ?
+		  AND (pm.from_user_id = ? OR pm.to_user_id = ?)
+		ORDER BY sm.created_at DESC, pm.id DESC
+		LIMIT ? OFFSET ?
+	`, sess.UserID, sess.UserID, sess.UserID, limit+1, offset)
+	if err != nil {
+		sendErrorResponse(w, "Failed to fetch starred messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	messages, err := scanMarkedMessages(rows)
+	if err != nil {
+		sendErrorResponse(w, "Failed to read starred messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"messages": messages,
+		"page":     page,
+		"hasMore":  hasMore,
+		"limit":    limit,
+	})
+}
+
+// GET /api/private-messages/pinned?target_user_id=N -> messages pinned in the conversation with N
+func GetPinnedMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil || sess == nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	targetUserID, err := strconv.Atoi(r.URL.Query().Get("target_user_id"))
+	if err != nil {
+		sendErrorResponse(w, "Invalid target user ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT
+			pm.id, pm.from_user_id, pm.to_user_id, pm.content,
+			pm.message_type, pm.is_read, pm.created_at,
+			u.username, u.profile_picture,
+			CASE WHEN sm.message_id IS NOT NULL THEN 1 ELSE 0 END AS is_starred,
+			1 AS is_pinned
+		FROM pinned_messages pin
+		JOIN private_messages pm ON pm.id = pin.message_id
+		JOIN users u ON pm.from_user_id = u.user_id
+		LEFT JOIN starred_messages sm ON sm.message_id = pm.id AND sm.user_id = ?
+		WHERE (pm.from_user_id = ? AND pm.to_user_id = ?)
+		   OR (pm.from_user_id = ? AND pm.to_user_id = ?)
+		ORDER BY pin.pinned_at DESC, pm.id DESC
+	`, sess.UserID, sess.UserID, targetUserID, targetUserID, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "Failed to fetch pinned messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	messages, err := scanMarkedMessages(rows)
+	if err != nil {
+		sendErrorResponse(w, "Failed to read pinned messages: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"messages": messages,
+	})
+}
+
+func scanMarkedMessages(rows *sql.Rows) ([]PrivateMessage, error) {
+	messages := []PrivateMessage{}
+	for rows.Next() {
+		var msg PrivateMessage
+		var profilePicture sql.NullString
+		var createdAt time.Time
+
+		if err := rows.Scan(
+			&msg.ID, &msg.FromUserID, &msg.ToUserID, &msg.Content,
+			&msg.MessageType, &msg.IsRead, &createdAt,
+			&msg.Username, &profilePicture,
+			&msg.IsStarred, &msg.IsPinned,
+		); err != nil {
+			return nil, err
+		}
+
+		msg.CreatedAt = createdAt.Format(time.RFC3339)
+		if profilePicture.Valid {
+			msg.ProfilePicture = profilePicture.String
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
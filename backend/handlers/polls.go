@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	minPollOptions     = 2
+	maxPollOptions     = 10
+	maxPollQuestionLen = 300
+	maxPollOptionLen   = 100
+)
+
+type pollPayload struct {
+	Question       string   `json:"question"`
+	Options        []string `json:"options"`
+	MultipleChoice bool     `json:"multiple_choice"`
+	ClosesAt       string   `json:"closes_at,omitempty"` // RFC 3339, optional
+}
+
+type pollOptionDTO struct {
+	OptionID int64  `json:"option_id"`
+	Label    string `json:"label"`
+	Votes    int    `json:"votes"`
+}
+
+type pollDTO struct {
+	PollID         int64           `json:"poll_id"`
+	PostID         int64           `json:"post_id"`
+	Question       string          `json:"question"`
+	MultipleChoice bool            `json:"multiple_choice"`
+	ClosesAt       *time.Time      `json:"closes_at,omitempty"`
+	Closed         bool            `json:"closed"`
+	Voters         int             `json:"voters"`
+	Options        []pollOptionDTO `json:"options"`
+	MyVotes        []int64         `json:"my_votes,omitempty"`
+}
+
+// normalize trims the payload in place and checks the limits.
+func (p *pollPayload) normalize() error {
+	p.Question = strings.TrimSpace(p.Question)
+	if p.Question == "" || len(p.Question) > maxPollQuestionLen {
+		return fmt.Errorf("Poll question must be 1-%d characters", maxPollQuestionLen)
+	}
+	if len(p.Options) < minPollOptions || len(p.Options) > maxPollOptions {
+		return fmt.Errorf("A poll needs %d-%d options", minPollOptions, maxPollOptions)
+	}
+	seen := make(map[string]bool, len(p.Options))
+	for i, o := range p.Options {
+		o = strings.TrimSpace(o)
+		if o == "" || len(o) > maxPollOptionLen {
+			return fmt.Errorf("Poll options must be 1-%d characters", maxPollOptionLen)
+		}
+		if seen[strings.ToLower(o)] {
+			return errors.New("Poll options must be unique")
+		}
+		seen[strings.ToLower(o)] = true
+		p.Options[i] = o
+	}
+	if p.ClosesAt != "" {
+		t, err := time.Parse(time.RFC3339, p.ClosesAt)
+		if err != nil {
+			return errors.New("closes_at must be an RFC 3339 time")
+		}
+		if !t.After(time.Now()) {
+			return errors.New("closes_at must be in the future")
+		}
+		p.ClosesAt = t.UTC().Format(sqliteTimeLayout)
+	}
+	return nil
+}
+
+// insertPoll stores a normalized poll for postID.
+func insertPoll(ex sqlExecutor, postID int64, p pollPayload) error {
+	var closesAt any
+	if p.ClosesAt != "" {
+		closesAt = p.ClosesAt
+	}
+	res, err := ex.Exec(`INSERT INTO polls (post_id, question, multiple_choice, closes_at) VALUES (?, ?, ?, ?)`,
+		postID, p.Question, p.MultipleChoice, closesAt)
+	if err != nil {
+		return err
+	}
+	pollID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for i, label := range p.Options {
+		if _, err := ex.Exec(`INSERT INTO poll_options (poll_id, label, position) VALUES (?, ?, ?)`,
+			pollID, label, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPolls returns the polls of the given posts keyed by post id, with
+// tallies and, when userID is not 0, that user's votes.
+func loadPolls(postIDs []int64, userID int64) (map[int64]*pollDTO, error) {
+	polls := make(map[int64]*pollDTO)
+	if len(postIDs) == 0 {
+		return polls, nil
+	}
+
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(postIDs)), ",")
+	args := make([]any, len(postIDs))
+	for i, v := range postIDs {
+		args[i] = v
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+SELECT p.poll_id, p.post_id, p.question, p.multiple_choice, p.closes_at,
+(SELECT COUNT(DISTINCT v.user_id) FROM poll_votes v WHERE v.poll_id = p.poll_id) AS voters
+FROM polls p
+WHERE p.post_id IN (%s)`, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	byPoll := make(map[int64]*pollDTO)
+	pollIDs := []any{userID}
+	for rows.Next() {
+		var p pollDTO
+		var closesAt sql.NullTime
+		if err := rows.Scan(&p.PollID, &p.PostID, &p.Question, &p.MultipleChoice, &closesAt, &p.Voters); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if closesAt.Valid {
+			p.ClosesAt = &closesAt.Time
+			p.Closed = !closesAt.Time.After(time.Now())
+		}
+		p.Options = []pollOptionDTO{}
+		polls[p.PostID] = &p
+		byPoll[p.PollID] = &p
+		pollIDs = append(pollIDs, p.PollID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(byPoll) == 0 {
+		return polls, nil
+	}
+
+	rows, err = db.Query(fmt.Sprintf(`
+SELECT o.poll_id, o.option_id, o.label, COUNT(v.user_id) AS votes,
+COALESCE(MAX(v.user_id = ?), 0) AS mine
+FROM poll_options o
+LEFT JOIN poll_votes v ON v.option_id = o.option_id
+WHERE o.poll_id IN (%s)
+GROUP BY o.option_id
+ORDER BY o.poll_id, o.position`, strings.TrimRight(strings.Repeat("?,", len(byPoll)), ",")), pollIDs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pollID int64
+		var o pollOptionDTO
+		var mine bool
+		if err := rows.Scan(&pollID, &o.OptionID, &o.Label, &o.Votes, &mine); err != nil {
+			return nil, err
+		}
+		p := byPoll[pollID]
+		p.Options = append(p.Options, o)
+		if mine && userID != 0 {
+			p.MyVotes = append(p.MyVotes, o.OptionID)
+		}
+	}
+	return polls, rows.Err()
+}
+
+func attachPolls(posts []postDTO, ids []int64, userID int64) error {
+	polls, err := loadPolls(ids, userID)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Poll = polls[posts[i].PostID]
+	}
+	return nil
+}
+
+// /api/posts/{id}/poll        GET the poll, POST { pollPayload } adds one to a post (author only)
+// /api/posts/{id}/poll/vote   POST { "option_ids": [..] } casts or replaces a ballot, DELETE retracts it
+func handlePoll(w http.ResponseWriter, r *http.Request, postID int64, sub string) {
+	var userID int64
+	sess, sessErr := GetSession(r)
+	if sessErr == nil {
+		userID = sess.UserID
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		polls, err := loadPolls([]int64{postID}, userID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (load poll)", http.StatusInternalServerError)
+			return
+		}
+		if polls[postID] == nil {
+			sendErrorResponse(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "data": polls[postID]})
+		return
+	case sub == "" && r.Method == http.MethodPost, sub == "vote" && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+	case sub == "" || sub == "vote":
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if sessErr != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if sub == "" {
+		handleAddPoll(w, r, postID, userID)
+		return
+	}
+	handlePollVote(w, r, postID, userID)
+}
+
+func handleAddPoll(w http.ResponseWriter, r *http.Request, postID, userID int64) {
+	var authorID int64
+	if err := db.QueryRow(`SELECT user_id FROM posts WHERE post_id = ?`, postID).Scan(&authorID); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if authorID != userID {
+		sendErrorResponse(w, "Only the author can add a poll", http.StatusForbidden)
+		return
+	}
+
+	var p pollPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := p.normalize(); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var exists int
+	if err := db.QueryRow(`SELECT 1 FROM polls WHERE post_id = ?`, postID).Scan(&exists); err == nil {
+		sendErrorResponse(w, "Post already has a poll", http.StatusConflict)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	if err := insertPoll(tx, postID, p); err != nil {
+		sendErrorResponse(w, "DB error (insert poll)", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	emitPollUpdate(w, postID, userID)
+}
+
+func handlePollVote(w http.ResponseWriter, r *http.Request, postID, userID int64) {
+	var pollID int64
+	var multiple bool
+	var closesAt sql.NullTime
+	var locked bool
+	if err := db.QueryRow(`SELECT pl.poll_id, pl.multiple_choice, pl.closes_at, p.locked_at IS NOT NULL
+		FROM polls pl JOIN posts p ON p.post_id = pl.post_id
+		WHERE pl.post_id = ?`, postID).
+		Scan(&pollID, &multiple, &closesAt, &locked); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if closesAt.Valid && !closesAt.Time.After(time.Now()) {
+		sendErrorResponse(w, "Poll is closed", http.StatusConflict)
+		return
+	}
+	if locked && !isModerator(userID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
+
+	var optionIDs []int64
+	if r.Method == http.MethodPost {
+		var body struct {
+			OptionIDs []int64 `json:"option_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		valid, err := loadIDs(`SELECT option_id FROM poll_options WHERE poll_id = ?`, pollID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (load options)", http.StatusInternalServerError)
+			return
+		}
+		isOption := make(map[int64]bool, len(valid))
+		for _, id := range valid {
+			isOption[id] = true
+		}
+		seen := make(map[int64]bool)
+		for _, id := range body.OptionIDs {
+			if !isOption[id] {
+				sendErrorResponse(w, fmt.Sprintf("Option %d is not part of this poll", id), http.StatusBadRequest)
+				return
+			}
+			if !seen[id] {
+				seen[id] = true
+				optionIDs = append(optionIDs, id)
+			}
+		}
+		if len(optionIDs) == 0 {
+			sendErrorResponse(w, "Choose at least one option", http.StatusBadRequest)
+			return
+		}
+		if !multiple && len(optionIDs) > 1 {
+			sendErrorResponse(w, "This poll allows a single choice", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, pollID, userID); err != nil {
+		sendErrorResponse(w, "DB error (clear vote)", http.StatusInternalServerError)
+		return
+	}
+	for _, id := range optionIDs {
+		if _, err := tx.Exec(`INSERT INTO poll_votes (poll_id, option_id, user_id) VALUES (?, ?, ?)`,
+			pollID, id, userID); err != nil {
+			sendErrorResponse(w, "DB error (vote)", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	emitPollUpdate(w, postID, userID)
+}
+
+// emitPollUpdate broadcasts fresh tallies as poll.updated and answers the
+// request with the poll as userID sees it.
+func emitPollUpdate(w http.ResponseWriter, postID, userID int64) {
+	polls, err := loadPolls([]int64{postID}, userID)
+	if err != nil || polls[postID] == nil {
+		sendErrorResponse(w, "DB error (load poll)", http.StatusInternalServerError)
+		return
+	}
+	poll := polls[postID]
+
+	public := *poll
+	public.MyVotes = nil
+	Emit("poll.updated", map[string]any{
+		"post_id": postID,
+		"poll":    public,
+	})
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    poll,
+	})
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Statements run before a post row is deleted. PRAGMA foreign_keys is off,
+// so the ON DELETE CASCADE clauses in schema.sql never fire on their own.
+var postCleanup = []string{
+	`DELETE FROM reactions WHERE post_id = ?1 OR comment_id IN (SELECT comment_id FROM comments WHERE post_id = ?1)`,
+	`DELETE FROM notifications WHERE post_id = ?`,
+	`DELETE FROM mentions WHERE post_id = ?`,
+	`DELETE FROM comments WHERE post_id = ?`,
+	`DELETE FROM post_categories WHERE post_id = ?`,
+	`DELETE FROM post_tags WHERE post_id = ?`,
+	`DELETE FROM bookmarks WHERE post_id = ?`,
+	`DELETE FROM post_views WHERE post_id = ?`,
+	`DELETE FROM post_revisions WHERE post_id = ?`,
+	`DELETE FROM poll_votes WHERE poll_id IN (SELECT poll_id FROM polls WHERE post_id = ?)`,
+	`DELETE FROM poll_options WHERE poll_id IN (SELECT poll_id FROM polls WHERE post_id = ?)`,
+	`DELETE FROM polls WHERE post_id = ?`,
+}
+
+type updatePostPayload struct {
+	Title      *string   `json:"title"`
+	Content    *string   `json:"content"`
+	Categories *[]int64  `json:"categories"`
+	Tags       *[]string `json:"tags"`
+}
+
+type postRevisionDTO struct {
+	RevisionID       int64     `json:"revision_id"`
+	PostID           int64     `json:"post_id"`
+	Title            string    `json:"title"`
+	Content          string    `json:"content"`
+	EditedBy         int64     `json:"edited_by"`
+	EditedByUsername string    `json:"edited_by_username"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// canModifyPost reports whether userID may edit or delete a post written by authorID.
+func canModifyPost(userID, authorID int64) bool {
+	return userID == authorID || isModerator(userID)
+}
+
+// PUT /api/posts/{id} replaces title, content and categories (and tags when given).
+// PATCH /api/posts/{id} changes only the fields present in the body.
+func handleUpdatePost(w http.ResponseWriter, r *http.Request, postID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload updatePostPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut && (payload.Title == nil || payload.Content == nil || payload.Categories == nil) {
+		sendErrorResponse(w, "PUT requires title, content and categories", http.StatusBadRequest)
+		return
+	}
+
+	var authorID int64
+	var oldTitle, oldContent string
+	if err := db.QueryRow(`SELECT user_id, title, content FROM posts WHERE post_id=?`, postID).
+		Scan(&authorID, &oldTitle, &oldContent); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !canModifyPost(sess.UserID, authorID) {
+		sendErrorResponse(w, "You can only edit your own posts", http.StatusForbidden)
+		return
+	}
+
+	title, content := oldTitle, oldContent
+	if payload.Title != nil {
+		title = strings.TrimSpace(*payload.Title)
+	}
+	if payload.Content != nil {
+		content = strings.TrimSpace(*payload.Content)
+	}
+	if len(title) < 3 {
+		sendErrorResponse(w, "Title must be at least 3 characters", http.StatusBadRequest)
+		return
+	}
+	if len(content) < 5 {
+		sendErrorResponse(w, "Content must be at least 5 characters", http.StatusBadRequest)
+		return
+	}
+	if payload.Categories != nil && len(*payload.Categories) == 0 {
+		sendErrorResponse(w, "Select at least one category", http.StatusBadRequest)
+		return
+	}
+	if payload.Categories != nil {
+		// A post may stay in a category archived after it was filed there;
+		// only newly added categories have to be live.
+		current, err := loadIDs(`SELECT category_id FROM post_categories WHERE post_id = ?`, postID)
+		if err != nil {
+			sendErrorResponse(w, "DB error (load categories)", http.StatusInternalServerError)
+			return
+		}
+		filed := make(map[int64]bool, len(current))
+		for _, id := range current {
+			filed[id] = true
+		}
+		var added []int64
+		for _, id := range *payload.Categories {
+			if !filed[id] {
+				added = append(added, id)
+			}
+		}
+		if err := validateCategoryIDs(added); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var tags []string
+	if payload.Tags != nil {
+		if tags, err = normalizeTags(*payload.Tags); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var added []mentionTarget
+	if title != oldTitle || content != oldContent {
+		if _, err := tx.Exec(`INSERT INTO post_revisions (post_id, title, content, edited_by) VALUES (?, ?, ?, ?)`,
+			postID, oldTitle, oldContent, sess.UserID); err != nil {
+			sendErrorResponse(w, "DB error (save revision)", http.StatusInternalServerError)
+			return
+		}
+		if _, err := tx.Exec(`UPDATE posts SET title=?, content=?, edited_at=CURRENT_TIMESTAMP WHERE post_id=?`,
+			title, content, postID); err != nil {
+			sendErrorResponse(w, "DB error (update post)", http.StatusInternalServerError)
+			return
+		}
+		if added, err = replaceMentions(tx, authorID, postID, 0, title+"\n"+content); err != nil {
+			sendErrorResponse(w, "DB error (mentions)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if payload.Categories != nil {
+		if _, err := tx.Exec(`DELETE FROM post_categories WHERE post_id=?`, postID); err != nil {
+			sendErrorResponse(w, "DB error (unlink categories)", http.StatusInternalServerError)
+			return
+		}
+		if err := linkCategories(tx, postID, *payload.Categories); err != nil {
+			sendErrorResponse(w, "DB error (link category)", http.StatusInternalServerError)
+			return
+		}
+	}
+	if payload.Tags != nil {
+		if err := replaceTags(tx, postID, tags); err != nil {
+			sendErrorResponse(w, "DB error (link tags)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	post, err := loadPost(postID, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load post)", http.StatusInternalServerError)
+		return
+	}
+
+	Emit("post.updated", map[string]any{
+		"post_id": postID,
+	})
+	emitMentions(added, authorID, post.Username, postID, 0, content)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Post updated",
+		"data":    post,
+	})
+}
+
+// DELETE /api/posts/{id}
+func handleDeletePost(w http.ResponseWriter, r *http.Request, postID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var authorID int64
+	var image, thumbnail sql.NullString
+	if err := db.QueryRow(`SELECT user_id, image, image_thumbnail FROM posts WHERE post_id=?`, postID).
+		Scan(&authorID, &image, &thumbnail); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !canModifyPost(sess.UserID, authorID) {
+		sendErrorResponse(w, "You can only delete your own posts", http.StatusForbidden)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range postCleanup {
+		if _, err := tx.Exec(stmt, postID); err != nil {
+			sendErrorResponse(w, "DB error (delete post data)", http.StatusInternalServerError)
+			return
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM posts WHERE post_id=?`, postID); err != nil {
+		sendErrorResponse(w, "DB error (delete post)", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+
+	removeUpload(image.String)
+	removeUpload(thumbnail.String)
+
+	Emit("post.deleted", map[string]any{
+		"post_id": postID,
+	})
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Post deleted",
+		"post_id": postID,
+	})
+}
+
+// GET /api/posts/{id}/revisions -> prior versions, newest first (author and moderators only)
+func handleListPostRevisions(w http.ResponseWriter, r *http.Request, postID int64) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var authorID int64
+	if err := db.QueryRow(`SELECT user_id FROM posts WHERE post_id=?`, postID).Scan(&authorID); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	if !canModifyPost(sess.UserID, authorID) {
+		sendErrorResponse(w, "Only the author and moderators can view revisions", http.StatusForbidden)
+		return
+	}
+
+	rows, err := db.Query(`
+SELECT pr.revision_id, pr.post_id, pr.title, pr.content, pr.edited_by, u.username, pr.created_at
+FROM post_revisions pr
+JOIN users u ON u.user_id = pr.edited_by
+WHERE pr.post_id = ?
+ORDER BY pr.revision_id DESC`, postID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list revisions)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []postRevisionDTO{}
+	for rows.Next() {
+		var rev postRevisionDTO
+		if err := rows.Scan(&rev.RevisionID, &rev.PostID, &rev.Title, &rev.Content, &rev.EditedBy, &rev.EditedByUsername, &rev.CreatedAt); err != nil {
+			sendErrorResponse(w, "DB error (scan revision)", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, rev)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    items,
+	})
+}
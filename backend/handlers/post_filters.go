@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"realtimeforum/backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Same layout as CURRENT_TIMESTAMP, so bounds compare correctly as text.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+var errLoginRequired = errors.New("login required")
+
+// addPostFilters adds the feed filters found in q to sb and args:
+//
+//	author=name            posts by the user with that username
+//	author_id=id           posts by the user with that id
+//	tag=a,b                posts carrying every listed tag (tag may repeat)
+//	mine=true              posts by the session user
+//	liked_by_me=true       posts the session user liked
+//	commented_by_me=true   posts the session user commented on
+//	from=, to=             creation date range, RFC 3339 or YYYY-MM-DD (to is inclusive)
+//
+// The *_me filters return errLoginRequired when userID is 0.
+func addPostFilters(q url.Values, userID int64, sb *strings.Builder, args *[]any) error {
+	if author := strings.TrimSpace(q.Get("author")); author != "" {
+		addWhere(sb, `u.username = ? COLLATE NOCASE`)
+		*args = append(*args, author)
+	}
+	if v := strings.TrimSpace(q.Get("author_id")); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return errors.New("author_id must be a user id")
+		}
+		addWhere(sb, `p.user_id = ?`)
+		*args = append(*args, id)
+	}
+
+	if err := addTagFilter(splitTagList(q["tag"]), sb, args); err != nil {
+		return err
+	}
+
+	mine, liked, commented := q.Get("mine") == "true", q.Get("liked_by_me") == "true", q.Get("commented_by_me") == "true"
+	if (mine || liked || commented) && userID == 0 {
+		return errLoginRequired
+	}
+	if mine {
+		addWhere(sb, `p.user_id = ?`)
+		*args = append(*args, userID)
+	}
+	if liked {
+		addWhere(sb, `EXISTS (SELECT 1 FROM reactions lr WHERE lr.post_id = p.post_id AND lr.comment_id IS NULL AND lr.user_id = ? AND lr.type = 'like')`)
+		*args = append(*args, userID)
+	}
+	if commented {
+		addWhere(sb, `EXISTS (SELECT 1 FROM comments mc WHERE mc.post_id = p.post_id AND mc.user_id = ?)`)
+		*args = append(*args, userID)
+	}
+
+	if v := q.Get("from"); v != "" {
+		from, _, err := parseDateParam(v)
+		if err != nil {
+			return errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+		addWhere(sb, `p.created_at >= ?`)
+		*args = append(*args, from.Format(sqliteTimeLayout))
+	}
+	if v := q.Get("to"); v != "" {
+		to, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		}
+		if dateOnly {
+			addWhere(sb, `p.created_at < ?`)
+			*args = append(*args, to.AddDate(0, 0, 1).Format(sqliteTimeLayout))
+		} else {
+			addWhere(sb, `p.created_at <= ?`)
+			*args = append(*args, to.Format(sqliteTimeLayout))
+		}
+	}
+	return nil
+}
+
+// addListFilters adds the GET /api/posts filters to a post query: category_id,
+// the addPostFilters parameters and search. It returns the FTS5 MATCH
+// expression the search was joined on, "" when there is none.
+func addListFilters(q url.Values, userID int64, joins, where *strings.Builder, joinArgs, whereArgs *[]any) (string, error) {
+	if catID := toInt64(q.Get("category_id"), 0); catID > 0 {
+		joins.WriteString(`JOIN post_categories pc ON pc.post_id = p.post_id `)
+		addWhere(where, `pc.category_id = ?`)
+		*whereArgs = append(*whereArgs, catID)
+	}
+	if err := addPostFilters(q, userID, where, whereArgs); err != nil {
+		return "", err
+	}
+
+	// Full-text search ranks by BM25 (title weighted above content); without
+	// FTS5 every term must appear as a substring of the title or content.
+	terms := parseSearchQuery(strings.TrimSpace(q.Get("search")))
+	if len(terms) == 0 {
+		return "", nil
+	}
+	if !models.FullTextSearch {
+		for _, t := range terms {
+			addWhere(where, `(p.title LIKE ? ESCAPE '\' OR p.content LIKE ? ESCAPE '\')`)
+			*whereArgs = append(*whereArgs, likePattern(t.Text), likePattern(t.Text))
+		}
+		return "", nil
+	}
+	matchQuery := ftsQuery(terms)
+	joins.WriteString(`JOIN (SELECT rowid AS post_id, rank AS score FROM posts_fts WHERE posts_fts MATCH ?) s
+ON s.post_id = p.post_id `)
+	*joinArgs = append(*joinArgs, matchQuery)
+	return matchQuery, nil
+}
+
+// listNarrowingParams are the addListFilters parameters other than category_id.
+var listNarrowingParams = []string{"author", "author_id", "tag", "mine", "liked_by_me", "commented_by_me", "from", "to", "search"}
+
+// narrowsListing reports whether q filters posts beyond category_id, in which
+// case a page of results is not the whole category.
+func narrowsListing(q url.Values) bool {
+	for _, k := range listNarrowingParams {
+		if strings.TrimSpace(q.Get(k)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDateParam accepts YYYY-MM-DD (reported as dateOnly) or an RFC 3339 time, returned in UTC.
+func parseDateParam(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, v)
+	return t.UTC(), false, err
+}
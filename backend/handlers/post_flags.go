@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Pinned posts are listed ahead of the first feed page; this caps how many.
+const maxPinnedPosts = 10
+
+const postLockedMessage = "This post is locked: it no longer accepts comments, reactions or votes"
+
+// pinnedClause matches posts pinned in the current listing: global pins, plus
+// pins to the category being browsed (its id is the clause's argument, 0 for
+// the unfiltered feed).
+const pinnedClause = `(p.pinned_at IS NOT NULL AND (p.pinned_category_id IS NULL OR p.pinned_category_id = ?))`
+
+// /api/posts/{id}/pin    PUT { "category_id": 3 } pins to a category (no body pins globally), DELETE unpins
+// /api/posts/{id}/lock   PUT locks the post, DELETE unlocks it
+//
+// Moderators only. Both emit post.updated with the new flags.
+func handlePostFlag(w http.ResponseWriter, r *http.Request, postID int64, flag string) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isModerator(sess.UserID) {
+		sendErrorResponse(w, "Only moderators can pin or lock posts", http.StatusForbidden)
+		return
+	}
+
+	var exists int
+	if err := db.QueryRow(`SELECT 1 FROM posts WHERE post_id = ?`, postID).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			sendErrorResponse(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+
+	set := r.Method != http.MethodDelete
+	switch {
+	case flag == "pin" && set:
+		var p struct {
+			CategoryID *int64 `json:"category_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil && err != io.EOF {
+			sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if p.CategoryID != nil {
+			var linked int
+			err := db.QueryRow(`SELECT 1 FROM post_categories WHERE post_id = ? AND category_id = ?`, postID, *p.CategoryID).Scan(&linked)
+			if err == sql.ErrNoRows {
+				sendErrorResponse(w, "The post is not in that category", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+				return
+			}
+		}
+		_, err = db.Exec(`UPDATE posts SET pinned_at = CURRENT_TIMESTAMP, pinned_category_id = ? WHERE post_id = ?`, p.CategoryID, postID)
+	case flag == "pin":
+		_, err = db.Exec(`UPDATE posts SET pinned_at = NULL, pinned_category_id = NULL WHERE post_id = ?`, postID)
+	case set:
+		_, err = db.Exec(`UPDATE posts SET locked_at = COALESCE(locked_at, CURRENT_TIMESTAMP) WHERE post_id = ?`, postID)
+	default:
+		_, err = db.Exec(`UPDATE posts SET locked_at = NULL WHERE post_id = ?`, postID)
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (update post)", http.StatusInternalServerError)
+		return
+	}
+
+	post, err := loadPost(postID, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load post)", http.StatusInternalServerError)
+		return
+	}
+
+	Emit("post.updated", map[string]any{
+		"post_id":            postID,
+		"pinned":             post.Pinned,
+		"pinned_category_id": post.PinnedCategoryID,
+		"locked":             post.Locked,
+	})
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    post,
+	})
+}
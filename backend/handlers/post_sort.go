@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sortNew           = "new"
+	sortTop           = "top"
+	sortHot           = "hot"
+	sortControversial = "controversial"
+	sortActive        = "active"
+	sortRelevance     = "relevance" // only with a full-text search
+)
+
+// Hours since the post was created, offset so brand new posts do not divide
+// by ~0. The placeholder is the feed's "now", fixed by the first page so
+// scores do not drift while a client pages through.
+const postAgeHours = `((julianday(?) - julianday(p.created_at)) * 24 + 2)`
+
+// Sort keys per mode, most significant first. The feed is ordered by every
+// key descending, and a cursor records the keys of the last post on a page.
+// All modes except new and relevance read the counters in post_stats (joined
+// as ps), so no aggregation is needed to sort. Every ? is bound to "now".
+//
+//	top:           net score (likes - dislikes)
+//	hot:           net score plus comments, decaying with the square of the age
+//	controversial: total votes scaled by how evenly they split
+//	active:        latest comment, or creation when there are none
+//	relevance:     BM25 rank of the full-text match (s.score, lower is better)
+var postSortKeys = map[string][]string{
+	sortNew: {`p.created_at`, `p.post_id`},
+	sortTop: {`ps.likes - ps.dislikes`, `p.created_at`, `p.post_id`},
+	sortHot: {`(ps.likes - ps.dislikes + ps.comments) / ` + postAgeHours + ` / ` + postAgeHours,
+		`p.created_at`, `p.post_id`},
+	sortControversial: {`(ps.likes + ps.dislikes) * MIN(ps.likes, ps.dislikes) * 1.0 / MAX(ps.likes, ps.dislikes, 1)`,
+		`ps.likes + ps.dislikes`, `p.created_at`, `p.post_id`},
+	sortActive:    {`ps.last_activity_at`, `p.post_id`},
+	sortRelevance: {`-s.score`, `p.created_at`, `p.post_id`},
+}
+
+// Values for the window parameter, as SQLite datetime modifiers. The window
+// limits the feed to posts created within it.
+var postSortWindows = map[string]string{
+	"day":   "-1 day",
+	"week":  "-7 days",
+	"month": "-1 month",
+	"year":  "-1 year",
+}
+
+var errBadCursor = errors.New("invalid cursor")
+
+// feedCursor is handed to clients base64-encoded as next_cursor.
+type feedCursor struct {
+	Sort string `json:"s"`
+	Now  string `json:"n"`
+	Keys []any  `json:"k"`
+}
+
+// sortKeyColumns selects each sort key as sort_k0, sort_k1, ... and returns
+// the matching ORDER BY list and the "now" arguments for the select.
+func sortKeyColumns(sort, now string) (columns, orderBy string, args []any) {
+	var cols, order []string
+	for i, k := range postSortKeys[sort] {
+		alias := "sort_k" + strconv.Itoa(i)
+		cols = append(cols, k+" AS "+alias)
+		order = append(order, alias+" DESC")
+		args = appendNow(args, k, now)
+	}
+	return strings.Join(cols, ",\n"), strings.Join(order, ", "), args
+}
+
+// afterCursor is the WHERE clause selecting posts that sort after c.
+func afterCursor(c feedCursor) (clause string, args []any) {
+	keys := postSortKeys[c.Sort]
+	for _, k := range keys {
+		args = appendNow(args, k, c.Now)
+	}
+	args = append(args, c.Keys...)
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(keys)), ",")
+	return "(" + strings.Join(keys, ", ") + ") < (" + placeholders + ")", args
+}
+
+func appendNow(args []any, expr, now string) []any {
+	for range strings.Count(expr, "?") {
+		args = append(args, now)
+	}
+	return args
+}
+
+func encodeCursor(c feedCursor) string {
+	for i, v := range c.Keys {
+		if t, ok := v.(time.Time); ok {
+			c.Keys[i] = t.UTC().Format(sqliteTimeLayout)
+		}
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (feedCursor, error) {
+	var c feedCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errBadCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, errBadCursor
+	}
+	keys, ok := postSortKeys[c.Sort]
+	if !ok || len(c.Keys) != len(keys) {
+		return c, errBadCursor
+	}
+	if _, err := time.Parse(sqliteTimeLayout, c.Now); err != nil {
+		return c, errBadCursor
+	}
+	for i, v := range c.Keys {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				c.Keys[i] = n
+			} else if f, err := v.Float64(); err == nil {
+				c.Keys[i] = f
+			} else {
+				return c, errBadCursor
+			}
+		case string:
+		default:
+			return c, errBadCursor
+		}
+	}
+	return c, nil
+}
@@ -3,8 +3,13 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"realtimeforum/backend/markdown"
 	"strconv"
 	"strings"
 	"time"
@@ -21,19 +26,60 @@ type postDTO struct {
 	Username   string       `json:"username"`
 	Title      string       `json:"title"`
 	Content    string       `json:"content"`
+	ContentHTML string      `json:"content_html"`
 	Image      *string      `json:"image,omitempty"`
 	CreatedAt  time.Time    `json:"created_at"`
 	Likes      int          `json:"likes"`
 	Dislikes   int          `json:"dislikes"`
 	Categories []categoryDTO `json:"categories"`
+	Tags       []string     `json:"tags"`
 	MyReaction string `json:"my_reaction,omitempty"`
+	CommentCount   int        `json:"comment_count"`
+	ProfilePicture string     `json:"profile_picture,omitempty"`
+	EditedAt       *time.Time `json:"edited_at,omitempty"`
+	ImageThumbnail *string    `json:"image_thumbnail,omitempty"`
+	Views          int          `json:"views"`
+	Pinned         bool         `json:"pinned"`
+	PinnedCategoryID *int64     `json:"pinned_category_id,omitempty"`
+	Locked         bool         `json:"locked"`
+	Match          *searchMatch `json:"match,omitempty"`
+	Trending       *trendingDTO `json:"trending,omitempty"`
+	Poll           *pollDTO     `json:"poll,omitempty"`
+	Bookmarked     bool         `json:"bookmarked"`
+	BookmarkFolder string       `json:"bookmark_folder,omitempty"`
+	BookmarkedAt   *time.Time   `json:"bookmarked_at,omitempty"`
 }
 
+// postSelect loads everything postDTO needs except categories; the first
+// argument is the session user id (0 when logged out) for my_reaction.
+// Extra columns can be slotted in between postColumns and postFrom and
+// passed to scanPost.
+const postSelect = postColumns + postFrom
+
+const postColumns = `
+SELECT p.post_id, p.user_id, u.username, p.title, p.content, p.image, p.created_at,
+COALESCE(ps.likes, 0) AS likes,
+COALESCE(ps.dislikes, 0) AS dislikes,
+ur.type AS my_reaction,
+COALESCE(ps.comments, 0) AS comment_count,
+u.profile_picture, p.edited_at, p.image_thumbnail,
+COALESCE(ps.views, 0) AS views,
+p.pinned_at IS NOT NULL AS pinned, p.pinned_category_id, p.locked_at IS NOT NULL AS locked`
+
+const postFrom = `
+FROM posts p
+JOIN users u ON u.user_id = p.user_id
+LEFT JOIN post_stats ps ON ps.post_id = p.post_id
+LEFT JOIN reactions ur ON ur.post_id = p.post_id AND ur.comment_id IS NULL AND ur.user_id = ?
+`
+
 type createPostPayload struct {
 	Title      string  `json:"title"`
 	Content    string  `json:"content"`
 	Categories []int64 `json:"categories"`
+	Tags       []string `json:"tags,omitempty"`
 	Image      *string `json:"image,omitempty"`
+	Poll       *pollPayload `json:"poll,omitempty"`
 }
 
 // /api/posts
@@ -56,27 +102,47 @@ func handleCreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var payload createPostPayload
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		sendErrorResponse(w, "Invalid JSON body", http.StatusBadRequest)
+	payload, imageHeader, err := parseCreatePostRequest(w, r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	title := strings.TrimSpace(payload.Title)
 	content := strings.TrimSpace(payload.Content)
-	if len(title) < 3 {
-		sendErrorResponse(w, "Title must be at least 3 characters", http.StatusBadRequest)
+	if err := validatePost(title, content, payload.Categories); err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if len(content) < 5 {
-		sendErrorResponse(w, "Content must be at least 5 characters", http.StatusBadRequest)
+	tags, err := normalizeTags(payload.Tags)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if len(payload.Categories) == 0 {
-		sendErrorResponse(w, "Select at least one category", http.StatusBadRequest)
-		return
+	if payload.Poll != nil {
+		if err := payload.Poll.normalize(); err != nil {
+			sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	}
 
+	var imageURL, thumbURL *string
+	if imageHeader != nil {
+		img, thumb, err := savePostImage(imageHeader)
+		if err != nil {
+			sendErrorResponse(w, "Error processing image: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		imageURL, thumbURL = &img, &thumb
+	}
+	committed := false
+	defer func() {
+		if !committed && imageURL != nil {
+			removeUpload(*imageURL)
+			removeUpload(*thumbURL)
+		}
+	}()
+
 	tx, err := db.Begin()
 	if err != nil {
 		sendErrorResponse(w, "DB error (begin tx)", http.StatusInternalServerError)
@@ -84,60 +150,232 @@ func handleCreatePost(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	res, err := tx.Exec(`INSERT INTO posts (user_id, title, content, image, created_at) 
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
-		session.UserID, title, content, payload.Image)
+	np := newPost{
+		UserID:         session.UserID,
+		Title:          title,
+		Content:        content,
+		Categories:     payload.Categories,
+		Tags:           tags,
+		Image:          imageURL,
+		ImageThumbnail: thumbURL,
+		Poll:           payload.Poll,
+	}
+	postID, mentioned, err := insertPost(tx, np)
 	if err != nil {
-		sendErrorResponse(w, "DB error (insert post)", http.StatusInternalServerError)
+		sendErrorResponse(w, "DB error ("+err.Error()+")", http.StatusInternalServerError)
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return
+	}
+	committed = true
+
+	announcePost(postID, np, session.Username, mentioned)
+	// return the created post (minimal)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "Post created",
+		"post_id": postID,
+	})
+}
+
+// newPost is what insertPost writes; Image and ImageThumbnail are URL paths
+// returned by savePostImage.
+type newPost struct {
+	UserID         int64
+	Title          string
+	Content        string
+	Categories     []int64
+	Tags           []string // already normalized
+	Image          *string
+	ImageThumbnail *string
+	Poll           *pollPayload // already normalized
+}
+
+func validatePost(title, content string, categories []int64) error {
+	if len(title) < 3 {
+		return errors.New("Title must be at least 3 characters")
+	}
+	if len(content) < 5 {
+		return errors.New("Content must be at least 5 characters")
+	}
+	if len(categories) == 0 {
+		return errors.New("Select at least one category")
+	}
+	return validateCategoryIDs(categories)
+}
+
+// insertPost writes a post with its categories, tags and mentions inside tx. The
+// caller commits and then calls announcePost. Errors name the failed step.
+func insertPost(tx *sql.Tx, np newPost) (int64, []mentionTarget, error) {
+	res, err := tx.Exec(`INSERT INTO posts (user_id, title, content, image, image_thumbnail, created_at) 
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		np.UserID, np.Title, np.Content, np.Image, np.ImageThumbnail)
+	if err != nil {
+		return 0, nil, errors.New("insert post")
+	}
+
 	postID, err := res.LastInsertId()
 	if err != nil {
-		sendErrorResponse(w, "DB error (post id)", http.StatusInternalServerError)
-		return
+		return 0, nil, errors.New("post id")
 	}
 
 	// link categories
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?, ?)`)
-	if err != nil {
-		sendErrorResponse(w, "DB error (prepare link cats)", http.StatusInternalServerError)
-		return
+	if err := linkCategories(tx, postID, np.Categories); err != nil {
+		return 0, nil, errors.New("link category")
+	}
+	if err := linkTags(tx, postID, np.Tags); err != nil {
+		return 0, nil, errors.New("link tags")
 	}
-	defer stmt.Close()
 
-	for _, cid := range payload.Categories {
-		if _, err := stmt.Exec(postID, cid); err != nil {
-			sendErrorResponse(w, "DB error (link category)", http.StatusInternalServerError)
-			return
+	if np.Poll != nil {
+		if err := insertPoll(tx, postID, *np.Poll); err != nil {
+			return 0, nil, errors.New("insert poll")
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
-		return
+	mentioned, err := recordMentions(tx, np.UserID, postID, 0, np.Title+"\n"+np.Content)
+	if err != nil {
+		return 0, nil, errors.New("mentions")
 	}
+	return postID, mentioned, nil
+}
 
-	Emit("post.created", map[string]any{
-	"post_id": postID, // int64 from res.LastInsertId()
-	})
-	// return the created post (minimal)
-	json.NewEncoder(w).Encode(map[string]any{
-		"success": true,
-		"message": "Post created",
+// announcePost broadcasts a committed post and notifies the users it mentions.
+func announcePost(postID int64, np newPost, authorName string, mentioned []mentionTarget) {
+	// Followers of the post's categories get their own copy, flagged.
+	followers := postFollowers(postID, np.UserID)
+	skip := make(map[int]bool, len(followers))
+	for uid := range followers {
+		skip[int(uid)] = true
+	}
+	EmitExcept("post.created", map[string]any{
 		"post_id": postID,
-	})
+	}, skip)
+	for uid, categoryIDs := range followers {
+		EmitToUser(int(uid), "post.created", map[string]any{
+			"post_id":             postID,
+			"followed":            true,
+			"followed_categories": categoryIDs,
+		})
+	}
+	emitMentions(mentioned, np.UserID, authorName, postID, 0, np.Content)
 }
 
+// parseCreatePostRequest reads a post from either a JSON body or a
+// multipart/form-data body (title, content, categories, tags, image file).
+// Images are only accepted as uploads, never as a client-supplied URL.
+func parseCreatePostRequest(w http.ResponseWriter, r *http.Request) (createPostPayload, *multipart.FileHeader, error) {
+	var payload createPostPayload
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			return payload, nil, errors.New("Invalid JSON body")
+		}
+		if payload.Image != nil && *payload.Image != "" {
+			return payload, nil, errors.New("Images must be uploaded as a file (multipart/form-data)")
+		}
+		return payload, nil, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadSize+1<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		return payload, nil, errors.New("Error parsing form data: " + err.Error())
+	}
+
+	payload.Title = r.FormValue("title")
+	payload.Content = r.FormValue("content")
+	for _, v := range r.MultipartForm.Value["categories"] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			cid, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return payload, nil, errors.New("Invalid category id: " + part)
+			}
+			payload.Categories = append(payload.Categories, cid)
+		}
+	}
+
+	payload.Tags = splitTagList(r.MultipartForm.Value["tags"])
+
+	if v := r.FormValue("poll"); v != "" {
+		payload.Poll = &pollPayload{}
+		if err := json.Unmarshal([]byte(v), payload.Poll); err != nil {
+			return payload, nil, errors.New("Invalid poll JSON")
+		}
+	}
+
+	files := r.MultipartForm.File["image"]
+	if len(files) == 0 {
+		return payload, nil, nil
+	}
+	return payload, files[0], nil
+}
+
+// savePostImage stores an uploaded post image plus its thumbnail and returns both URL paths.
+func savePostImage(header *multipart.FileHeader) (string, string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	imageURL, fsPath, err := storeImageUpload(file, header, postImageDir, postImageURLPrefix, decodableImageTypes)
+	if err != nil {
+		return "", "", err
+	}
+
+	thumbPath, err := makeThumbnail(fsPath)
+	if err != nil {
+		os.Remove(fsPath)
+		return "", "", fmt.Errorf("invalid image: %v", err)
+	}
+	return imageURL, postImageURLPrefix + filepath.Base(thumbPath), nil
+}
+
+// GET /api/posts
+//
+// Pages are either keyset-based (cursor=<next_cursor from the previous page>)
+// or, for older clients, offset-based (page=N). include_total=true adds the
+// number of posts matching the filters. Pinned posts come before the first
+// page and do not count towards limit.
 func handleListPosts(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	page := clamp(toInt(q.Get("page"), 1), 1, 1000000)
 	limit := clamp(toInt(q.Get("limit"), 10), 1, 50)
 	offset := (page - 1) * limit
 
-	search := strings.TrimSpace(q.Get("search"))
 	catID := toInt64(q.Get("category_id"), 0)
 
+	sort := q.Get("sort")
+	if _, ok := postSortKeys[sort]; sort != "" && (!ok || sort == sortRelevance) {
+		sendErrorResponse(w, "sort must be one of new, top, hot, controversial, active", http.StatusBadRequest)
+		return
+	}
+	window := q.Get("window")
+	if _, ok := postSortWindows[window]; window != "" && window != "all" && !ok {
+		sendErrorResponse(w, "window must be one of day, week, month, year, all", http.StatusBadRequest)
+		return
+	}
+
+	var cursor *feedCursor
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			sendErrorResponse(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		cursor, offset = &c, 0
+	}
+	now := time.Now().UTC().Format(sqliteTimeLayout)
+	if cursor != nil {
+		now = cursor.Now
+	}
+
 	// Try session; if not logged-in, userID=0 (won't match)
 	var userID int64 = 0
 	if sess, err := GetSession(r); err == nil {
@@ -145,45 +383,86 @@ func handleListPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var (
-		args      []any
-		sbSelect  strings.Builder
+		joinArgs  []any
+		whereArgs []any
 		sbWhere   strings.Builder
 		sbJoins   strings.Builder
 	)
 
-	sbSelect.WriteString(`
-SELECT p.post_id, p.user_id, u.username, p.title, p.content, p.image, p.created_at,
-COALESCE(SUM(CASE WHEN r.type='like' THEN 1 ELSE 0 END),0) AS likes,
-COALESCE(SUM(CASE WHEN r.type='dislike' THEN 1 ELSE 0 END),0) AS dislikes,
-ur.type AS my_reaction
-FROM posts p
-JOIN users u ON u.user_id = p.user_id
-LEFT JOIN reactions r ON r.post_id = p.post_id AND r.comment_id IS NULL
-LEFT JOIN reactions ur ON ur.post_id = p.post_id AND ur.comment_id IS NULL AND ur.user_id = ?
-`)
+	matchQuery, err := addListFilters(q, userID, &sbJoins, &sbWhere, &joinArgs, &whereArgs)
+	if err != nil {
+		if err == errLoginRequired {
+			sendErrorResponse(w, "Log in to filter by your own activity", http.StatusUnauthorized)
+			return
+		}
+		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if matchQuery != "" && sort == "" {
+		sort = sortRelevance
+	}
 
-	args = append(args, userID)
+	if sort == "" {
+		sort = sortNew
+	}
+	if cursor != nil && cursor.Sort != sort {
+		sendErrorResponse(w, "Cursor belongs to a different sort order", http.StatusBadRequest)
+		return
+	}
+	if modifier, ok := postSortWindows[window]; ok {
+		addWhere(&sbWhere, `p.created_at >= datetime(?, ?)`)
+		whereArgs = append(whereArgs, now, modifier)
+	}
 
-	if catID > 0 {
-		sbJoins.WriteString(`JOIN post_categories pc ON pc.post_id = p.post_id `)
-		addWhere(&sbWhere, `pc.category_id = ?`)
-		args = append(args, catID)
+	var total *int
+	if q.Get("include_total") == "true" {
+		countQuery := `SELECT COUNT(DISTINCT p.post_id) FROM posts p JOIN users u ON u.user_id = p.user_id ` + sbJoins.String()
+		if sbWhere.Len() > 0 {
+			countQuery += "WHERE " + sbWhere.String()
+		}
+		var n int
+		if err := db.QueryRow(countQuery, append(joinArgs, whereArgs...)...).Scan(&n); err != nil {
+			sendErrorResponse(w, "DB error (count posts)", http.StatusInternalServerError)
+			return
+		}
+		total = &n
 	}
-	if search != "" {
-		addWhere(&sbWhere, `(p.title LIKE ? OR p.content LIKE ?)`)
-		args = append(args, "%"+search+"%", "%"+search+"%")
+
+	// Posts pinned in this listing lead the first page, on top of limit, and
+	// are left out of the paged results.
+	firstPage := cursor == nil && offset == 0
+	pinnedQuery := postSelect + sbJoins.String() + "WHERE "
+	if sbWhere.Len() > 0 {
+		pinnedQuery += sbWhere.String() + " AND "
+	}
+	pinnedQuery += pinnedClause + `
+ORDER BY p.pinned_at DESC, p.post_id DESC
+LIMIT ?`
+	pinnedArgs := append([]any{userID}, joinArgs...)
+	pinnedArgs = append(pinnedArgs, whereArgs...)
+	pinnedArgs = append(pinnedArgs, catID, maxPinnedPosts)
+	addWhere(&sbWhere, "NOT "+pinnedClause)
+	whereArgs = append(whereArgs, catID)
+
+	if cursor != nil {
+		clause, cursorArgs := afterCursor(*cursor)
+		addWhere(&sbWhere, clause)
+		whereArgs = append(whereArgs, cursorArgs...)
 	}
 
-	query := sbSelect.String() + sbJoins.String()
+	keyColumns, orderBy, args := sortKeyColumns(sort, now)
+	query := postColumns + ",\n" + keyColumns + postFrom + sbJoins.String()
 	if sbWhere.Len() > 0 {
 		query += "WHERE " + sbWhere.String()
 	}
 	query += `
-GROUP BY p.post_id
-ORDER BY p.created_at DESC
+ORDER BY ` + orderBy + `
 LIMIT ? OFFSET ?`
 
-	args = append(args, limit, offset)
+	args = append(args, userID)
+	args = append(args, joinArgs...)
+	args = append(args, whereArgs...)
+	args = append(args, limit+1, offset)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -194,19 +473,50 @@ LIMIT ? OFFSET ?`
 
 	var posts []postDTO
 	var postIDs []int64
+	var lastKeys []any
+	hasMore := false
 
 	for rows.Next() {
-		var p postDTO
-		var myReaction sql.NullString
-		if err := rows.Scan(&p.PostID, &p.UserID, &p.Username, &p.Title, &p.Content, &p.Image, &p.CreatedAt, &p.Likes, &p.Dislikes, &myReaction); err != nil {
+		keys := make([]any, len(postSortKeys[sort]))
+		dest := make([]any, len(keys))
+		for i := range keys {
+			dest[i] = &keys[i]
+		}
+		p, err := scanPost(rows, dest...)
+		if err != nil {
 			sendErrorResponse(w, "DB error (scan)", http.StatusInternalServerError)
 			return
 		}
-		if myReaction.Valid {
-			p.MyReaction = myReaction.String
+		if len(posts) == limit {
+			hasMore = true
+			break
 		}
 		posts = append(posts, p)
 		postIDs = append(postIDs, p.PostID)
+		lastKeys = keys
+	}
+	if err := rows.Err(); err != nil {
+		sendErrorResponse(w, "DB error (list posts)", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	if firstPage {
+		pinned, err := loadPinnedPosts(pinnedQuery, pinnedArgs)
+		if err != nil {
+			sendErrorResponse(w, "DB error (pinned posts)", http.StatusInternalServerError)
+			return
+		}
+		for _, p := range pinned {
+			postIDs = append(postIDs, p.PostID)
+		}
+		posts = append(pinned, posts...)
+		// Only the plain newest-first category listing counts as a visit;
+		// another sort, a search or a filter may not have shown the newest posts.
+		_, windowed := postSortWindows[window]
+		if catID > 0 && userID != 0 && sort == sortNew && !windowed && !narrowsListing(q) {
+			markCategoryVisited(userID, catID)
+		}
 	}
 
 	if len(postIDs) > 0 {
@@ -214,16 +524,162 @@ LIMIT ? OFFSET ?`
 			sendErrorResponse(w, "DB error (load categories)", http.StatusInternalServerError)
 			return
 		}
+		if err := attachTags(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load tags)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachPolls(posts, postIDs, userID); err != nil {
+			sendErrorResponse(w, "DB error (load polls)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachBookmarks(posts, postIDs, userID); err != nil {
+			sendErrorResponse(w, "DB error (load bookmarks)", http.StatusInternalServerError)
+			return
+		}
+		if matchQuery != "" {
+			if err := attachSearchMatches(posts, postIDs, matchQuery); err != nil {
+				sendErrorResponse(w, "DB error (load search highlights)", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	resp := map[string]any{
+		"success":     true,
+		"data":        posts,
+		"page":        page,
+		"limit":       limit,
+		"sort":        sort,
+		"has_more":    hasMore,
+		"next_cursor": nil,
+	}
+	if hasMore {
+		resp["next_cursor"] = encodeCursor(feedCursor{Sort: sort, Now: now, Keys: lastKeys})
+	}
+	if total != nil {
+		resp["total"] = *total
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func loadPinnedPosts(query string, args []any) ([]postDTO, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []postDTO
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// GET /api/posts/{id}
+func handleGetPost(w http.ResponseWriter, r *http.Request, postID int64) {
+	var userID int64 = 0
+	if sess, err := GetSession(r); err == nil {
+		userID = sess.UserID
+	}
+
+	p, err := loadPost(postID, userID)
+	if err == sql.ErrNoRows {
+		sendErrorResponse(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (load post)", http.StatusInternalServerError)
+		return
+	}
+	if p.UserID != userID {
+		recordView(postID, viewerKey(r, userID))
 	}
 
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
-		"data":    posts,
-		"page":    page,
-		"limit":   limit,
+		"data":    p,
 	})
 }
 
+// loadPost returns a single post as seen by userID, or sql.ErrNoRows.
+func loadPost(postID, userID int64) (*postDTO, error) {
+	rows, err := db.Query(postSelect+`WHERE p.post_id = ?`, userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		err := rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	p, err := scanPost(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []postDTO{p}
+	if err := attachCategories(posts, []int64{p.PostID}); err != nil {
+		return nil, err
+	}
+	if err := attachTags(posts, []int64{p.PostID}); err != nil {
+		return nil, err
+	}
+	if err := attachPolls(posts, []int64{p.PostID}, userID); err != nil {
+		return nil, err
+	}
+	if err := attachBookmarks(posts, []int64{p.PostID}, userID); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// scanPost reads a postSelect row; extra receives any columns added after postColumns.
+func scanPost(rows *sql.Rows, extra ...any) (postDTO, error) {
+	var p postDTO
+	var myReaction, picture sql.NullString
+	var editedAt sql.NullTime
+	dest := []any{&p.PostID, &p.UserID, &p.Username, &p.Title, &p.Content, &p.Image, &p.CreatedAt,
+		&p.Likes, &p.Dislikes, &myReaction, &p.CommentCount, &picture, &editedAt, &p.ImageThumbnail, &p.Views,
+		&p.Pinned, &p.PinnedCategoryID, &p.Locked}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return p, err
+	}
+	p.ContentHTML = markdown.Render(p.Content)
+	if editedAt.Valid {
+		p.EditedAt = &editedAt.Time
+	}
+	if myReaction.Valid {
+		p.MyReaction = myReaction.String
+	}
+	if picture.Valid {
+		p.ProfilePicture = picture.String
+	}
+	return p, nil
+}
+
+func linkCategories(tx *sql.Tx, postID int64, categoryIDs []int64) error {
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO post_categories (post_id, category_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, cid := range categoryIDs {
+		if _, err := stmt.Exec(postID, cid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 func attachCategories(posts []postDTO, ids []int64) error {
 	// build IN (?, ?, ?)
@@ -240,7 +696,7 @@ SELECT pc.post_id, c.category_id, c.name
 FROM post_categories pc 
 JOIN categories c ON c.category_id = pc.category_id
 WHERE pc.post_id IN (%s)
-ORDER BY c.name ASC`, placeholders)
+ORDER BY c.position ASC, c.name ASC`, placeholders)
 
 	rows, err := db.Query(q, args...)
 	if err != nil {
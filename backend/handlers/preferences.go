@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Delivery modes stored in notification_preferences.delivery
+const (
+	deliveryLive = "live" // stored in the notification list and pushed over the WebSocket
+	deliveryList = "list" // stored in the notification list only
+	deliveryOff  = "off"  // dropped
+)
+
+// Typing indicators are never stored, so "list" behaves like "off" for them.
+const prefTyping = "typing"
+
+var preferenceKinds = []string{notifyComment, notifyReply, notifyReaction, notifyMention, notifyMessage, prefTyping}
+
+type preferencesPayload struct {
+	Notifications map[string]string `json:"notifications"`
+	EmailDigest   *bool             `json:"email_digest,omitempty"`
+}
+
+func notificationDelivery(userID int64, kind string) string {
+	var mode string
+	if err := db.QueryRow(`SELECT delivery FROM notification_preferences WHERE user_id = ? AND kind = ?`,
+		userID, kind).Scan(&mode); err != nil {
+		return deliveryLive
+	}
+	return mode
+}
+
+func isConversationMuted(userID, otherUserID int64) bool {
+	var ok int
+	err := db.QueryRow(`SELECT 1 FROM muted_conversations WHERE user_id = ? AND other_user_id = ?`,
+		userID, otherUserID).Scan(&ok)
+	return err == nil
+}
+
+// isPostMuted reports whether the post belongs to any category userID muted.
+func isPostMuted(userID, postID int64) bool {
+	var ok int
+	err := db.QueryRow(`
+SELECT 1 FROM post_categories pc
+JOIN muted_categories mc ON mc.category_id = pc.category_id
+WHERE pc.post_id = ? AND mc.user_id = ?
+LIMIT 1`, postID, userID).Scan(&ok)
+	return err == nil
+}
+
+// liveEventAllowed decides whether a realtime event may be pushed to userID.
+// EmitToUser consults it for every event; unknown event types always pass.
+func liveEventAllowed(userID int64, eventType string, data any) bool {
+	switch eventType {
+	case "notification":
+		m, _ := data.(map[string]any)
+		n, ok := m["notification"].(notificationDTO)
+		if !ok {
+			return true
+		}
+		if notificationDelivery(userID, n.Kind) != deliveryLive {
+			return false
+		}
+		if n.Kind == notifyMessage && isConversationMuted(userID, n.ActorID) {
+			return false
+		}
+		return n.PostID == nil || !isPostMuted(userID, *n.PostID)
+	case "mention":
+		if notificationDelivery(userID, notifyMention) != deliveryLive {
+			return false
+		}
+		return !isPostMuted(userID, eventInt(data, "post_id"))
+	case "user_typing":
+		if notificationDelivery(userID, prefTyping) != deliveryLive {
+			return false
+		}
+		return !isConversationMuted(userID, eventInt(data, "from_user_id"))
+	}
+	return true
+}
+
+// mutedAudience returns the users who must not receive a broadcast event.
+// Emit consults it before handing the message to the hub.
+func mutedAudience(eventType string, data any) map[int]bool {
+	switch eventType {
+	case "post.created", "comment.created":
+	default:
+		return nil
+	}
+
+	rows, err := db.Query(`
+SELECT DISTINCT mc.user_id FROM muted_categories mc
+JOIN post_categories pc ON pc.category_id = mc.category_id
+WHERE pc.post_id = ?`, eventInt(data, "post_id"))
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	excluded := make(map[int]bool)
+	for rows.Next() {
+		var uid int
+		if err := rows.Scan(&uid); err == nil {
+			excluded[uid] = true
+		}
+	}
+	return excluded
+}
+
+func eventInt(data any, key string) int64 {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return 0
+	}
+	switch v := m[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
+
+// GET /api/preferences -> delivery mode per kind, muted conversations and categories, followed categories
+// PUT /api/preferences { "notifications": { "reaction": "list", "typing": "off" }, "email_digest": true }
+func PreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !updateNotificationPreferences(w, r, sess.UserID) {
+			return
+		}
+	default:
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefs := make(map[string]string, len(preferenceKinds))
+	for _, k := range preferenceKinds {
+		prefs[k] = deliveryLive
+	}
+	rows, err := db.Query(`SELECT kind, delivery FROM notification_preferences WHERE user_id = ?`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load preferences)", http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var kind, mode string
+		if err := rows.Scan(&kind, &mode); err == nil {
+			prefs[kind] = mode
+		}
+	}
+	rows.Close()
+
+	conversations, err := loadIDs(`SELECT other_user_id FROM muted_conversations WHERE user_id = ? ORDER BY other_user_id`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load muted conversations)", http.StatusInternalServerError)
+		return
+	}
+	categories, err := loadIDs(`SELECT category_id FROM muted_categories WHERE user_id = ? ORDER BY category_id`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load muted categories)", http.StatusInternalServerError)
+		return
+	}
+
+	followed, err := loadIDs(`SELECT category_id FROM category_follows WHERE user_id = ? ORDER BY category_id`, sess.UserID)
+	if err != nil {
+		sendErrorResponse(w, "DB error (load followed categories)", http.StatusInternalServerError)
+		return
+	}
+
+	var emailDigest bool
+	db.QueryRow(`SELECT enabled FROM email_digests WHERE user_id = ?`, sess.UserID).Scan(&emailDigest)
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":             true,
+		"notifications":       prefs,
+		"muted_conversations": conversations,
+		"muted_categories":    categories,
+		"followed_categories": followed,
+		"email_digest":        emailDigest,
+	})
+}
+
+func updateNotificationPreferences(w http.ResponseWriter, r *http.Request, userID int64) bool {
+	var p preferencesPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		sendErrorResponse(w, "Invalid JSON", http.StatusBadRequest)
+		return false
+	}
+
+	for kind, mode := range p.Notifications {
+		if !isPreferenceKind(kind) {
+			sendErrorResponse(w, "Unknown notification kind: "+kind, http.StatusBadRequest)
+			return false
+		}
+		if mode != deliveryLive && mode != deliveryList && mode != deliveryOff {
+			sendErrorResponse(w, "delivery must be 'live', 'list' or 'off'", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		sendErrorResponse(w, "DB error (begin)", http.StatusInternalServerError)
+		return false
+	}
+	defer tx.Rollback()
+
+	for kind, mode := range p.Notifications {
+		if kind == prefTyping && mode == deliveryList {
+			mode = deliveryOff
+		}
+		if _, err := tx.Exec(`
+INSERT INTO notification_preferences (user_id, kind, delivery) VALUES (?, ?, ?)
+ON CONFLICT(user_id, kind) DO UPDATE SET delivery = excluded.delivery`, userID, kind, mode); err != nil {
+			sendErrorResponse(w, "DB error (save preference)", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	if p.EmailDigest != nil {
+		if _, err := tx.Exec(`
+INSERT INTO email_digests (user_id, enabled) VALUES (?, ?)
+ON CONFLICT(user_id) DO UPDATE SET enabled = excluded.enabled`, userID, *p.EmailDigest); err != nil {
+			sendErrorResponse(w, "DB error (save email digest)", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		sendErrorResponse(w, "DB error (commit)", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func isPreferenceKind(kind string) bool {
+	for _, k := range preferenceKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func loadIDs(query string, args ...any) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// /api/preferences/conversations/{userID} and /api/preferences/categories/{categoryID}
+// POST mutes, DELETE unmutes.
+func PreferencesSubresourceRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/preferences/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	targetID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || targetID <= 0 {
+		sendErrorResponse(w, "Invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess, err := GetSession(r)
+	if err != nil {
+		sendErrorResponse(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	muted := r.Method == http.MethodPost
+
+	var table, column, existsQuery string
+	switch parts[0] {
+	case "conversations":
+		table, column = "muted_conversations", "other_user_id"
+		existsQuery = `SELECT 1 FROM users WHERE user_id = ?`
+		if targetID == sess.UserID {
+			sendErrorResponse(w, "Cannot mute yourself", http.StatusBadRequest)
+			return
+		}
+	case "categories":
+		table, column = "muted_categories", "category_id"
+		existsQuery = `SELECT 1 FROM categories WHERE category_id = ?`
+	default:
+		sendErrorResponse(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if muted {
+		var ok int
+		if err := db.QueryRow(existsQuery, targetID).Scan(&ok); err != nil {
+			if err == sql.ErrNoRows {
+				sendErrorResponse(w, "Not found", http.StatusNotFound)
+				return
+			}
+			sendErrorResponse(w, "DB error", http.StatusInternalServerError)
+			return
+		}
+		_, err = db.Exec(`INSERT OR IGNORE INTO `+table+` (user_id, `+column+`) VALUES (?, ?)`, sess.UserID, targetID)
+	} else {
+		_, err = db.Exec(`DELETE FROM `+table+` WHERE user_id = ? AND `+column+` = ?`, sess.UserID, targetID)
+	}
+	if err != nil {
+		sendErrorResponse(w, "DB error (mute)", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"id":      targetID,
+		"muted":   muted,
+	})
+}
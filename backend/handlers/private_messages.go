@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"realtimeforum/backend/markdown"
 	"strconv"
 	"time"
 )
@@ -13,11 +14,23 @@ type PrivateMessage struct {
 	FromUserID     int    `json:"from_user_id"`
 	ToUserID       int    `json:"to_user_id"`
 	Content        string `json:"content"`
+	ContentHTML    string `json:"content_html,omitempty"`
 	MessageType    string `json:"message_type"`
 	IsRead         bool   `json:"is_read"`
 	CreatedAt      string `json:"created_at"`
 	Username       string `json:"username,omitempty"`
 	ProfilePicture string `json:"profile_picture,omitempty"`
+	IsStarred      bool   `json:"is_starred"`
+	IsPinned       bool   `json:"is_pinned"`
+	Muted          bool   `json:"muted,omitempty"`
+}
+
+// renderContent fills ContentHTML for text messages; other message types
+// carry a URL or file reference in Content rather than Markdown.
+func (m *PrivateMessage) renderContent() {
+	if m.MessageType == "text" {
+		m.ContentHTML = markdown.Render(m.Content)
+	}
 }
 
 type SendMessageRequest struct {
@@ -73,14 +86,18 @@ func GetPrivateMessagesHandler(w http.ResponseWriter, r *http.Request) {
         SELECT 
             pm.id, pm.from_user_id, pm.to_user_id, pm.content, 
             pm.message_type, pm.is_read, pm.created_at,
-            u.username, u.profile_picture
+            u.username, u.profile_picture,
+            CASE WHEN sm.message_id IS NOT NULL THEN 1 ELSE 0 END AS is_starred,
+            CASE WHEN pin.message_id IS NOT NULL THEN 1 ELSE 0 END AS is_pinned
         FROM private_messages pm
         JOIN users u ON pm.from_user_id = u.user_id
+        LEFT JOIN starred_messages sm ON sm.message_id = pm.id AND sm.user_id = ?
+        LEFT JOIN pinned_messages pin ON pin.message_id = pm.id
         WHERE (pm.from_user_id = ? AND pm.to_user_id = ?) 
            OR (pm.from_user_id = ? AND pm.to_user_id = ?)
         ORDER BY pm.id DESC  -- CHANGED TO DESC FOR LATEST FIRST
         LIMIT ? OFFSET ?
-    `, sess.UserID, targetUserID, targetUserID, sess.UserID, limit, offset)
+    `, sess.UserID, sess.UserID, targetUserID, targetUserID, sess.UserID, limit, offset)
     
     if err != nil {
         sendErrorResponse(w, "Failed to fetch messages: "+err.Error(), http.StatusInternalServerError)
@@ -98,12 +115,14 @@ func GetPrivateMessagesHandler(w http.ResponseWriter, r *http.Request) {
             &msg.ID, &msg.FromUserID, &msg.ToUserID, &msg.Content,
             &msg.MessageType, &msg.IsRead, &createdAt,
             &msg.Username, &profilePicture,
+            &msg.IsStarred, &msg.IsPinned,
         )
         if err != nil {
             continue
         }
         
         msg.CreatedAt = createdAt.Format(time.RFC3339)
+        msg.renderContent()
         if profilePicture.Valid {
             msg.ProfilePicture = profilePicture.String
         }
@@ -210,13 +229,25 @@ func SendPrivateMessageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sentMessage.CreatedAt = createdAt.Format(time.RFC3339)
+	sentMessage.renderContent()
 	if profilePicture.Valid {
 		sentMessage.ProfilePicture = profilePicture.String
 	}
 
 	
 	
-	EmitToUser(req.ToUserID, "new_private_message", sentMessage)
+	// Muted conversations still get the message so an open chat stays in
+	// sync; the flag tells the client not to raise an alert for it.
+	liveMessage := sentMessage
+	liveMessage.Muted = isConversationMuted(int64(req.ToUserID), sess.UserID)
+	EmitToUser(req.ToUserID, "new_private_message", liveMessage)
+	notify(notification{
+		UserID:    int64(req.ToUserID),
+		ActorID:   sess.UserID,
+		Kind:      notifyMessage,
+		MessageID: messageID,
+		Excerpt:   req.Content,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -283,8 +314,10 @@ func markMessagesAsRead(userID, fromUserID int) {
 
 
 func EmitToUser(userID int, eventType string, data interface{}) {
-	
-	
+	if !liveEventAllowed(int64(userID), eventType, data) {
+		return
+	}
+
 	msg, err := json.Marshal(map[string]interface{}{
 		"type": eventType,
 		"data": data,
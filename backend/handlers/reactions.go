@@ -38,8 +38,10 @@ func handleReactPost(w http.ResponseWriter, r *http.Request, postID int64) {
 	}
 
 	// Ensure post exists
-	var ok int
-	if err := db.QueryRow(`SELECT 1 FROM posts WHERE post_id=?`, postID).Scan(&ok); err != nil {
+	var postAuthorID int64
+	var postTitle string
+	var locked bool
+	if err := db.QueryRow(`SELECT user_id, title, locked_at IS NOT NULL FROM posts WHERE post_id=?`, postID).Scan(&postAuthorID, &postTitle, &locked); err != nil {
 		if err == sql.ErrNoRows {
 			sendErrorResponse(w, "Post not found", http.StatusNotFound)
 			return
@@ -47,6 +49,10 @@ func handleReactPost(w http.ResponseWriter, r *http.Request, postID int64) {
 		sendErrorResponse(w, "DB error", http.StatusInternalServerError)
 		return
 	}
+	if locked && !isModerator(sess.UserID) {
+		sendErrorResponse(w, postLockedMessage, http.StatusForbidden)
+		return
+	}
 
 	tx, err := db.Begin()
 	if err != nil {
@@ -110,6 +116,16 @@ FROM reactions WHERE post_id=? AND comment_id IS NULL`, postID).Scan(&likes, &di
 	"likes":    likes,
 	"dislikes": dislikes,
 	})
+	if t != "" {
+		notify(notification{
+			UserID:  postAuthorID,
+			ActorID: sess.UserID,
+			Kind:    notifyReaction,
+			PostID:  postID,
+			Detail:  t,
+			Excerpt: postTitle,
+		})
+	}
 	
 	json.NewEncoder(w).Encode(map[string]any{
 		"success": true,
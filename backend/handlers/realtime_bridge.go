@@ -12,11 +12,26 @@ func SetHub(h *ws.Hub) { realtimeHub = h }
 
 // Emit a server-side event to all clients, JSON shape: {"type": "...", "data": {...}}
 func Emit(eventType string, data any) {
+	EmitExcept(eventType, data, nil)
+}
+
+// EmitExcept is Emit minus the given users, for events some users receive
+// in a personalised form instead.
+func EmitExcept(eventType string, data any, skip map[int]bool) {
 	if realtimeHub == nil { return }
 	msg, err := json.Marshal(map[string]any{
 		"type": eventType,
 		"data": data,
 	})
 	if err != nil { return }
+	excluded := mutedAudience(eventType, data)
+	for uid := range skip {
+		if excluded == nil { excluded = make(map[int]bool) }
+		excluded[uid] = true
+	}
+	if len(excluded) > 0 {
+		realtimeHub.BroadcastExceptUsers(excluded, msg)
+		return
+	}
 	realtimeHub.Broadcast <- msg
 }
@@ -0,0 +1,25 @@
+package handlers
+
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+func userRole(userID int64) string {
+	var role string
+	if err := db.QueryRow(`SELECT role FROM users WHERE user_id = ?`, userID).Scan(&role); err != nil {
+		return roleUser
+	}
+	return role
+}
+
+// isModerator is true for moderators and admins.
+func isModerator(userID int64) bool {
+	role := userRole(userID)
+	return role == roleModerator || role == roleAdmin
+}
+
+func isAdmin(userID int64) bool {
+	return userRole(userID) == roleAdmin
+}
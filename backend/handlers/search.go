@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const maxSearchTerms = 16
+
+// Markers wrapped around matched tokens by highlight() and snippet(). They are
+// swapped for <mark> tags only after the surrounding text is HTML-escaped.
+const (
+	matchOpen  = "\x02"
+	matchClose = "\x03"
+)
+
+type searchTerm struct {
+	Text   string
+	Prefix bool // foo* or "foo bar"*
+}
+
+// searchMatch is attached to posts found by a full-text search. Title and
+// Snippet are HTML-escaped with matches wrapped in <mark>.
+type searchMatch struct {
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"` // higher is more relevant
+}
+
+// parseSearchQuery splits user input into words and "quoted phrases". A
+// trailing * turns a word or phrase into a prefix query. Everything else is
+// treated as plain text, so FTS5 operators in the input have no effect.
+func parseSearchQuery(s string) []searchTerm {
+	var terms []searchTerm
+	for s = strings.TrimSpace(s); s != "" && len(terms) < maxSearchTerms; s = strings.TrimSpace(s) {
+		var text string
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				text, s = s[1:], ""
+			} else {
+				text, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(s, " \t\r\n\"")
+			if end < 0 {
+				end = len(s)
+			}
+			text, s = s[:end], s[end:]
+		}
+
+		prefix := strings.HasSuffix(text, "*")
+		if strings.HasPrefix(s, "*") {
+			prefix, s = true, s[1:]
+		}
+		text = strings.TrimSpace(strings.Trim(text, "*"))
+		if text != "" {
+			terms = append(terms, searchTerm{Text: text, Prefix: prefix})
+		}
+	}
+	return terms
+}
+
+// ftsQuery turns terms into an FTS5 MATCH expression. Every term is quoted,
+// so punctuation and keywords such as OR or NEAR are matched literally.
+func ftsQuery(terms []searchTerm) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		q := `"` + strings.ReplaceAll(t.Text, `"`, `""`) + `"`
+		if t.Prefix {
+			q += "*"
+		}
+		parts = append(parts, q)
+	}
+	return strings.Join(parts, " ")
+}
+
+// likePattern builds a substring pattern for LIKE ... ESCAPE '\'.
+func likePattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
+func markMatches(s string) string {
+	s = html.EscapeString(s)
+	return strings.NewReplacer(matchOpen, "<mark>", matchClose, "</mark>").Replace(s)
+}
+
+// attachSearchMatches fills posts[i].Match with highlighted text for query.
+func attachSearchMatches(posts []postDTO, ids []int64, query string) error {
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(ids)), ",")
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, query)
+	for _, v := range ids {
+		args = append(args, v)
+	}
+
+	q := fmt.Sprintf(`
+SELECT rowid,
+highlight(posts_fts, 0, char(2), char(3)),
+snippet(posts_fts, 1, char(2), char(3), '…', 24),
+-rank
+FROM posts_fts
+WHERE posts_fts MATCH ? AND rowid IN (%s)`, placeholders)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	idx := make(map[int64]int, len(posts))
+	for i := range posts {
+		idx[posts[i].PostID] = i
+	}
+
+	for rows.Next() {
+		var id int64
+		var m searchMatch
+		if err := rows.Scan(&id, &m.Title, &m.Snippet, &m.Score); err != nil {
+			return err
+		}
+		if i, ok := idx[id]; ok {
+			m.Title = markMatches(m.Title)
+			m.Snippet = markMatches(m.Snippet)
+			posts[i].Match = &m
+		}
+	}
+	return rows.Err()
+}
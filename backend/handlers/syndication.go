@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const syndicationTitle = "Real-Time Forum"
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Creator     string   `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// syndicationFeed is what both formats are rendered from.
+type syndicationFeed struct {
+	title   string
+	base    string // scheme://host the links are built on
+	self    string
+	updated time.Time
+	posts   []postDTO
+}
+
+// GET /api/posts.rss  and  GET /api/posts.atom
+//
+// The latest posts as RSS 2.0 or Atom, newest first. Takes the /api/posts
+// filters: category_id, author, author_id, tag, search, from, to, plus limit
+// (max 50).
+// Responses carry ETag and Last-Modified and honour conditional requests.
+func PostsRSSHandler(w http.ResponseWriter, r *http.Request) {
+	serveSyndication(w, r, "rss")
+}
+
+func PostsAtomHandler(w http.ResponseWriter, r *http.Request) {
+	serveSyndication(w, r, "atom")
+}
+
+func serveSyndication(w http.ResponseWriter, r *http.Request, format string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	feed, status, err := loadSyndicationFeed(r)
+	if err != nil {
+		sendErrorResponse(w, err.Error(), status)
+		return
+	}
+
+	var body []byte
+	var contentType string
+	if format == "atom" {
+		body, err = renderAtom(feed)
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		body, err = renderRSS(feed)
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	if err != nil {
+		sendErrorResponse(w, "Failed to render feed", http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", feed.updated.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	if notModified(r, etag, feed.updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the client
+// sent no entity tags.
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !updated.After(since)
+}
+
+// loadSyndicationFeed runs the handleListPosts query, newest first and as a
+// logged-out reader, and names the feed after its category or author filter.
+func loadSyndicationFeed(r *http.Request) (syndicationFeed, int, error) {
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 20), 1, 50)
+	feed := syndicationFeed{title: syndicationTitle, base: requestBaseURL(r)}
+	feed.self = feed.base + r.URL.RequestURI()
+
+	if catID := toInt64(q.Get("category_id"), 0); catID > 0 {
+		c, err := loadCategory(catID)
+		if err == sql.ErrNoRows {
+			return feed, http.StatusNotFound, fmt.Errorf("Category not found")
+		}
+		if err != nil {
+			return feed, http.StatusInternalServerError, fmt.Errorf("DB error (load category)")
+		}
+		feed.title += " – " + c.Name
+	}
+	author, authorID := strings.TrimSpace(q.Get("author")), strings.TrimSpace(q.Get("author_id"))
+	if author != "" || authorID != "" {
+		var name string
+		var err error
+		if authorID != "" {
+			err = db.QueryRow(`SELECT username FROM users WHERE user_id = ?`, toInt64(authorID, 0)).Scan(&name)
+		} else {
+			err = db.QueryRow(`SELECT username FROM users WHERE username = ? COLLATE NOCASE`, author).Scan(&name)
+		}
+		if err == sql.ErrNoRows {
+			return feed, http.StatusNotFound, fmt.Errorf("User not found")
+		}
+		if err != nil {
+			return feed, http.StatusInternalServerError, fmt.Errorf("DB error (load user)")
+		}
+		feed.title += " – posts by " + name
+	}
+
+	var (
+		joinArgs  []any
+		whereArgs []any
+		sbWhere   strings.Builder
+		sbJoins   strings.Builder
+	)
+	if _, err := addListFilters(q, 0, &sbJoins, &sbWhere, &joinArgs, &whereArgs); err != nil {
+		if err == errLoginRequired {
+			return feed, http.StatusBadRequest, fmt.Errorf("Feeds cannot filter by your own activity")
+		}
+		return feed, http.StatusBadRequest, err
+	}
+
+	query := postSelect + sbJoins.String()
+	if sbWhere.Len() > 0 {
+		query += "WHERE " + sbWhere.String()
+	}
+	query += `
+ORDER BY p.created_at DESC, p.post_id DESC
+LIMIT ?`
+	args := append([]any{0}, joinArgs...)
+	args = append(args, whereArgs...)
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return feed, http.StatusInternalServerError, fmt.Errorf("DB error (list posts)")
+	}
+	defer rows.Close()
+
+	var postIDs []int64
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return feed, http.StatusInternalServerError, fmt.Errorf("DB error (scan)")
+		}
+		feed.posts = append(feed.posts, p)
+		postIDs = append(postIDs, p.PostID)
+		if u := postUpdated(p); u.After(feed.updated) {
+			feed.updated = u
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return feed, http.StatusInternalServerError, fmt.Errorf("DB error (list posts)")
+	}
+	rows.Close()
+
+	if len(postIDs) > 0 {
+		if err := attachCategories(feed.posts, postIDs); err != nil {
+			return feed, http.StatusInternalServerError, fmt.Errorf("DB error (load categories)")
+		}
+	}
+	if feed.updated.IsZero() {
+		feed.updated = time.Unix(0, 0)
+	}
+	feed.updated = feed.updated.UTC().Truncate(time.Second)
+	return feed, 0, nil
+}
+
+func postUpdated(p postDTO) time.Time {
+	if p.EditedAt != nil && p.EditedAt.After(p.CreatedAt) {
+		return *p.EditedAt
+	}
+	return p.CreatedAt
+}
+
+// requestBaseURL is the scheme and host the request was made to, trusting
+// X-Forwarded-Proto from a reverse proxy.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// The frontend is a single page; posts are addressed by fragment.
+func postPermalink(base string, postID int64) string {
+	return fmt.Sprintf("%s/#post-%d", base, postID)
+}
+
+// syndicationHTML is the entry body: the rendered Markdown, led by the image.
+func syndicationHTML(base string, p postDTO) string {
+	if p.Image == nil || *p.Image == "" {
+		return p.ContentHTML
+	}
+	src := *p.Image
+	if strings.HasPrefix(src, "/") {
+		src = base + src
+	}
+	return `<p><img src="` + html.EscapeString(src) + `" alt=""></p>` + p.ContentHTML
+}
+
+func renderRSS(feed syndicationFeed) ([]byte, error) {
+	doc := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.title,
+			Link:          feed.base + "/",
+			Description:   "Latest posts on " + feed.title,
+			Self:          atomLink{Href: feed.self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: feed.updated.Format(time.RFC1123Z),
+		},
+	}
+	for _, p := range feed.posts {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        postPermalink(feed.base, p.PostID),
+			GUID:        postPermalink(feed.base, p.PostID),
+			Creator:     p.Username,
+			PubDate:     p.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: syndicationHTML(feed.base, p),
+		}
+		for _, c := range p.Categories {
+			item.Categories = append(item.Categories, c.Name)
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+	return marshalFeed(doc)
+}
+
+func renderAtom(feed syndicationFeed) ([]byte, error) {
+	doc := atomFeed{
+		Title:   feed.title,
+		ID:      feed.self,
+		Updated: feed.updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.self, Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.base + "/", Rel: "alternate", Type: "text/html"},
+		},
+	}
+	for _, p := range feed.posts {
+		link := postPermalink(feed.base, p.PostID)
+		entry := atomEntry{
+			Title:     p.Title,
+			ID:        link,
+			Link:      atomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Published: p.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   postUpdated(p).UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: p.Username},
+			Content:   atomContent{Type: "html", Body: syndicationHTML(feed.base, p)},
+		}
+		for _, c := range p.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c.Name})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return marshalFeed(doc)
+}
+
+func marshalFeed(doc any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
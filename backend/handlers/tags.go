@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxTagsPerPost = 5
+	minTagLength   = 2
+	maxTagLength   = 30
+)
+
+type tagCountDTO struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// normalizeTag lowercases s, drops a leading '#' and turns runs of spaces,
+// underscores and dashes into a single dash, so "#Go_Lang" and "go lang"
+// are both stored as "go-lang". Letters, digits, '+', '.' and '#' are kept
+// (c++, .net, c#); anything else is rejected.
+func normalizeTag(s string) (string, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '.' || r == '#':
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			dash = true
+		default:
+			return "", fmt.Errorf("Tag %q may only contain letters, digits, dashes and + . #", s)
+		}
+	}
+
+	tag := sb.String()
+	if n := utf8.RuneCountInString(tag); n < minTagLength || n > maxTagLength {
+		return "", fmt.Errorf("Tags must be %d to %d characters", minTagLength, maxTagLength)
+	}
+	return tag, nil
+}
+
+// normalizeTags normalizes and de-duplicates tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	out := []string{}
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		tag, err := normalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	if len(out) > maxTagsPerPost {
+		return nil, fmt.Errorf("A post can have at most %d tags", maxTagsPerPost)
+	}
+	return out, nil
+}
+
+// splitTagList reads tags from form values, each of which may be a
+// comma-separated list.
+func splitTagList(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+	return tags
+}
+
+// linkTags attaches already normalized tags to a post, creating new tags.
+func linkTags(tx *sql.Tx, postID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO post_tags (post_id, tag_id)
+			SELECT ?, tag_id FROM tags WHERE name = ?`, postID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceTags swaps a post's tags for tags.
+func replaceTags(tx *sql.Tx, postID int64, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_id = ?`, postID); err != nil {
+		return err
+	}
+	return linkTags(tx, postID, tags)
+}
+
+func attachTags(posts []postDTO, ids []int64) error {
+	placeholders := strings.TrimRight(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, v := range ids {
+		args[i] = v
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+SELECT pt.post_id, t.name
+FROM post_tags pt
+JOIN tags t ON t.tag_id = pt.tag_id
+WHERE pt.post_id IN (%s)
+ORDER BY t.name ASC`, placeholders), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	tmap := make(map[int64][]string)
+	for rows.Next() {
+		var pid int64
+		var name string
+		if err := rows.Scan(&pid, &name); err != nil {
+			return err
+		}
+		tmap[pid] = append(tmap[pid], name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].Tags = tmap[posts[i].PostID]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// addTagFilter narrows the feed to posts carrying every tag in tags.
+func addTagFilter(tags []string, sb *strings.Builder, args *[]any) error {
+	for _, t := range tags {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		tag, err := normalizeTag(t)
+		if err != nil {
+			return err
+		}
+		addWhere(sb, `EXISTS (SELECT 1 FROM post_tags ft JOIN tags t ON t.tag_id = ft.tag_id WHERE ft.post_id = p.post_id AND t.name = ?)`)
+		*args = append(*args, tag)
+	}
+	return nil
+}
+
+// GET /api/tags?q=go&limit=10 -> tags in use, most used first. With q, only
+// tags starting with q (after normalization) are returned.
+func TagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 10), 1, 50)
+
+	query := `
+SELECT t.name, COUNT(*) AS uses
+FROM tags t
+JOIN post_tags pt ON pt.tag_id = t.tag_id
+`
+	var args []any
+	if prefix := strings.TrimSpace(q.Get("q")); prefix != "" {
+		// A partial tag may still be too short to be valid, so only fold
+		// case and separators here instead of calling normalizeTag.
+		prefix = strings.ToLower(strings.TrimPrefix(prefix, "#"))
+		prefix = strings.Join(strings.FieldsFunc(prefix, func(r rune) bool {
+			return r == '_' || unicode.IsSpace(r)
+		}), "-")
+		query += `WHERE t.name LIKE ? ESCAPE '\' `
+		args = append(args, strings.TrimPrefix(likePattern(prefix), "%"))
+	}
+	query += `
+GROUP BY t.tag_id
+ORDER BY uses DESC, t.name ASC
+LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		sendErrorResponse(w, "DB error (list tags)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	items := []tagCountDTO{}
+	for rows.Next() {
+		var t tagCountDTO
+		if err := rows.Scan(&t.Name, &t.Count); err != nil {
+			sendErrorResponse(w, "DB error (scan tag)", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, t)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    items,
+	})
+}
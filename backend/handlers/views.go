@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Weights for /api/posts/trending: a comment says more about a post than a
+// reaction, and a reaction more than a view.
+const (
+	trendingViewWeight     = 1.0
+	trendingReactionWeight = 2.0
+	trendingCommentWeight  = 3.0
+)
+
+var trendingWindows = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+type trendingDTO struct {
+	Views     int     `json:"views"`
+	Comments  int     `json:"comments"`
+	Reactions int     `json:"reactions"`
+	Score     float64 `json:"score"`
+}
+
+type viewKey struct {
+	postID int64
+	viewer string
+}
+
+// Views are buffered here and written by RunViewFlusher in one transaction,
+// so opening a post never waits on the single database connection.
+var (
+	pendingViewsMu sync.Mutex
+	pendingViews   = make(map[viewKey]time.Time)
+)
+
+// viewerKey identifies a reader for de-duplication: the user id when logged
+// in, otherwise a hash of the client address and user agent.
+func viewerKey(r *http.Request, userID int64) string {
+	if userID != 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(host + "\x00" + r.UserAgent()))
+	return "a:" + hex.EncodeToString(sum[:12])
+}
+
+func recordView(postID int64, viewer string) {
+	pendingViewsMu.Lock()
+	pendingViews[viewKey{postID, viewer}] = time.Now().UTC()
+	pendingViewsMu.Unlock()
+}
+
+// RunViewFlusher writes buffered post views every interval. It never returns.
+func RunViewFlusher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := flushViews(); err != nil {
+			log.Printf("views: flush: %v", err)
+		}
+	}
+}
+
+// flushViews stores the buffered views. A viewer counts once per post;
+// viewing again only moves viewed_at, which is what trending looks at.
+func flushViews() error {
+	pendingViewsMu.Lock()
+	batch := pendingViews
+	pendingViews = make(map[viewKey]time.Time)
+	pendingViewsMu.Unlock()
+
+	if len(batch) == 0 || db == nil {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO post_views (post_id, viewer, viewed_at) VALUES (?, ?, ?)
+		ON CONFLICT(post_id, viewer) DO UPDATE SET viewed_at = excluded.viewed_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, at := range batch {
+		if _, err := stmt.Exec(k.postID, k.viewer, at.Format(sqliteTimeLayout)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GET /api/posts/trending?window=hour|day|week&limit=10
+//
+// Ranks posts by views, reactions and comments received within the window
+// (default day) ending now. Views are counted once per reader.
+func TrendingPostsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := clamp(toInt(q.Get("limit"), 10), 1, 50)
+	window := q.Get("window")
+	if window == "" {
+		window = "day"
+	}
+	span, ok := trendingWindows[window]
+	if !ok {
+		sendErrorResponse(w, "window must be one of hour, day, week", http.StatusBadRequest)
+		return
+	}
+	since := time.Now().UTC().Add(-span).Format(sqliteTimeLayout)
+
+	var userID int64
+	if sess, err := GetSession(r); err == nil {
+		userID = sess.UserID
+	}
+
+	query := `
+WITH recent AS (
+	SELECT post_id, COUNT(*) AS views, 0 AS comments, 0 AS reactions
+	FROM post_views WHERE viewed_at >= ? GROUP BY post_id
+	UNION ALL
+	SELECT post_id, 0, COUNT(*), 0
+	FROM comments WHERE created_at >= ? AND deleted_at IS NULL GROUP BY post_id
+	UNION ALL
+	SELECT post_id, 0, 0, COUNT(*)
+	FROM reactions WHERE comment_id IS NULL AND post_id IS NOT NULL AND created_at >= ? GROUP BY post_id
+), activity AS (
+	SELECT post_id, SUM(views) AS views, SUM(comments) AS comments, SUM(reactions) AS reactions,
+		SUM(views) * ? + SUM(reactions) * ? + SUM(comments) * ? AS score
+	FROM recent GROUP BY post_id
+)` + postColumns + `,
+t.views, t.comments, t.reactions, t.score` + postFrom + `JOIN activity t ON t.post_id = p.post_id
+ORDER BY t.score DESC, p.created_at DESC, p.post_id DESC
+LIMIT ?`
+
+	rows, err := db.Query(query, since, since, since,
+		trendingViewWeight, trendingReactionWeight, trendingCommentWeight, userID, limit)
+	if err != nil {
+		sendErrorResponse(w, "DB error (trending posts)", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	posts := []postDTO{}
+	var postIDs []int64
+	for rows.Next() {
+		var t trendingDTO
+		p, err := scanPost(rows, &t.Views, &t.Comments, &t.Reactions, &t.Score)
+		if err != nil {
+			sendErrorResponse(w, "DB error (scan)", http.StatusInternalServerError)
+			return
+		}
+		p.Trending = &t
+		posts = append(posts, p)
+		postIDs = append(postIDs, p.PostID)
+	}
+	if err := rows.Err(); err != nil {
+		sendErrorResponse(w, "DB error (trending posts)", http.StatusInternalServerError)
+		return
+	}
+	rows.Close()
+
+	if len(postIDs) > 0 {
+		if err := attachCategories(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load categories)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachTags(posts, postIDs); err != nil {
+			sendErrorResponse(w, "DB error (load tags)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachPolls(posts, postIDs, userID); err != nil {
+			sendErrorResponse(w, "DB error (load polls)", http.StatusInternalServerError)
+			return
+		}
+		if err := attachBookmarks(posts, postIDs, userID); err != nil {
+			sendErrorResponse(w, "DB error (load bookmarks)", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"data":    posts,
+		"window":  window,
+	})
+}
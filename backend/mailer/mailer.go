@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain-text e-mails.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer sends through an SMTP server. Username may be empty for servers
+// without authentication, such as a local stand-in used during development.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := m.Host + ":" + m.Port
+	return smtp.SendMail(addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// FileMailer appends every message to Path, or writes it to the log when Path is empty.
+type FileMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	raw := format(m.From, msg)
+	if m.Path == "" {
+		log.Printf("mail to %s:\n%s", msg.To, raw)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(raw, []byte("\r\n")...))
+	return err
+}
+
+// FromEnv builds the mailer selected by MAILER ("smtp", "file" or "log", the default).
+//
+//	smtp: SMTP_HOST, SMTP_PORT (default 25), SMTP_USERNAME, SMTP_PASSWORD
+//	file: MAIL_FILE (default mail.log)
+//
+// MAIL_FROM sets the sender for all of them.
+func FromEnv() Mailer {
+	from := envOr("MAIL_FROM", "forum@localhost")
+
+	switch strings.ToLower(os.Getenv("MAILER")) {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     envOr("SMTP_HOST", "localhost"),
+			Port:     envOr("SMTP_PORT", "25"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		return &FileMailer{Path: envOr("MAIL_FILE", "mail.log"), From: from}
+	default:
+		return &FileMailer{From: from}
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func format(from string, msg Message) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", sanitizeHeader(from))
+	fmt.Fprintf(&sb, "To: %s\r\n", sanitizeHeader(msg.To))
+	fmt.Fprintf(&sb, "Subject: %s\r\n", sanitizeHeader(msg.Subject))
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	sb.WriteString("\r\n")
+	return []byte(sb.String())
+}
+
+func sanitizeHeader(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
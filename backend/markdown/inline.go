@@ -0,0 +1,286 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// renderInline writes text with inline formatting. links is false inside
+// link text, where nested links are not allowed.
+func renderInline(sb *strings.Builder, text string, links bool) {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && isASCIIPunct(text[i+1]):
+			writeEscaped(sb, text[i+1:i+2])
+			i += 2
+			continue
+
+		case c == '\n':
+			sb.WriteString("<br>\n")
+			i++
+			continue
+
+		case c == '`':
+			if n := codeSpan(sb, text[i:]); n > 0 {
+				i += n
+				continue
+			}
+			run := countRun(text[i:], '`')
+			sb.WriteString(text[i : i+run])
+			i += run
+			continue
+
+		case c == '[' && links:
+			if n := link(sb, text[i:]); n > 0 {
+				i += n
+				continue
+			}
+
+		case c == '<' && links:
+			if n := angleAutolink(sb, text[i:]); n > 0 {
+				i += n
+				continue
+			}
+
+		case c == 'h' && links && (i == 0 || !isWordByte(text[i-1])):
+			if n := bareAutolink(sb, text[i:]); n > 0 {
+				i += n
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if n := emphasis(sb, text, i, links); n > 0 {
+				i += n
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		writeEscaped(sb, string(r))
+		i += size
+	}
+}
+
+// codeSpan renders `code` at the start of s and returns the bytes consumed, or 0.
+func codeSpan(sb *strings.Builder, s string) int {
+	run := countRun(s, '`')
+	for j := run; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return 0
+		}
+		j += k
+		if n := countRun(s[j:], '`'); n == run {
+			code := strings.ReplaceAll(s[run:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			sb.WriteString("<code>")
+			writeEscaped(sb, code)
+			sb.WriteString("</code>")
+			return j + n
+		} else {
+			j += n
+		}
+	}
+	return 0
+}
+
+// link renders [text](url) at the start of s. A link with an unsafe URL is
+// rendered as its text alone.
+func link(sb *strings.Builder, s string) int {
+	end := matchBracket(s, '[', ']')
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return 0
+	}
+	close := matchBracket(s[end+1:], '(', ')')
+	if close < 0 {
+		return 0
+	}
+	label := s[1:end]
+	dest := strings.TrimSpace(s[end+2 : end+1+close])
+	// Drop an optional "title".
+	if k := strings.IndexAny(dest, " \t\n"); k >= 0 {
+		dest = dest[:k]
+	}
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+
+	if href, ok := SafeURL(dest); ok {
+		writeLinkOpen(sb, href)
+		renderInline(sb, label, false)
+		sb.WriteString("</a>")
+	} else {
+		renderInline(sb, label, false)
+	}
+	return end + 1 + close + 1
+}
+
+// angleAutolink renders <https://example.com> at the start of s.
+func angleAutolink(sb *strings.Builder, s string) int {
+	end := strings.IndexByte(s, '>')
+	if end < 0 {
+		return 0
+	}
+	target := s[1:end]
+	if strings.ContainsAny(target, " \t\n<") || !hasWebScheme(target) {
+		return 0
+	}
+	href, ok := SafeURL(target)
+	if !ok {
+		return 0
+	}
+	writeLinkOpen(sb, href)
+	writeEscaped(sb, target)
+	sb.WriteString("</a>")
+	return end + 1
+}
+
+// bareAutolink renders a plain http(s) URL at the start of s, leaving
+// trailing punctuation outside the link.
+func bareAutolink(sb *strings.Builder, s string) int {
+	if !hasWebScheme(s) {
+		return 0
+	}
+	end := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '<' })
+	if end < 0 {
+		end = len(s)
+	}
+	target := strings.TrimRight(s[:end], ".,;:!?'\")]*_~")
+	if strings.Count(target, "(") > strings.Count(target, ")") && end > len(target) && s[len(target)] == ')' {
+		target = s[:len(target)+1]
+	}
+	if strings.Index(target, "://")+3 >= len(target) {
+		return 0
+	}
+	href, ok := SafeURL(target)
+	if !ok {
+		return 0
+	}
+	writeLinkOpen(sb, href)
+	writeEscaped(sb, target)
+	sb.WriteString("</a>")
+	return len(target)
+}
+
+// emphasis renders **strong**, *em*, _em_ or ~~del~~ starting at text[i].
+func emphasis(sb *strings.Builder, text string, i int, links bool) int {
+	c := text[i]
+	run := countRun(text[i:], c)
+	var delim, tag string
+	switch {
+	case c == '~' && run >= 2:
+		delim, tag = "~~", "del"
+	case c != '~' && run >= 2:
+		delim, tag = text[i:i+2], "strong"
+	case c != '~':
+		delim, tag = text[i:i+1], "em"
+	default:
+		return 0
+	}
+
+	// Underscores inside words (snake_case) are not emphasis.
+	if c == '_' && i > 0 && isWordByte(text[i-1]) {
+		return 0
+	}
+	start := i + len(delim)
+	if start >= len(text) || text[start] == ' ' || text[start] == '\n' {
+		return 0
+	}
+
+	for j := start; j < len(text); {
+		k := strings.Index(text[j:], delim)
+		if k < 0 {
+			return 0
+		}
+		j += k
+		after := j + len(delim)
+		if j > start && text[j-1] != ' ' && text[j-1] != '\n' && text[j-1] != '\\' &&
+			(c != '_' || after >= len(text) || !isWordByte(text[after])) &&
+			(len(delim) == 2 || after >= len(text) || text[after] != c) {
+			sb.WriteString("<" + tag + ">")
+			renderInline(sb, text[start:j], links)
+			sb.WriteString("</" + tag + ">")
+			return after - i
+		}
+		j++
+	}
+	return 0
+}
+
+func writeLinkOpen(sb *strings.Builder, href string) {
+	sb.WriteString(`<a href="`)
+	writeEscaped(sb, href)
+	sb.WriteString(`" rel="nofollow noopener noreferrer">`)
+}
+
+// SafeURL reports whether u may be used as a link target: http, https or
+// mailto URLs, or paths on this site. It returns the URL to use.
+func SafeURL(u string) (string, bool) {
+	u = strings.TrimSpace(u)
+	if u == "" || strings.ContainsAny(u, "\x00\n\r\t") {
+		return "", false
+	}
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") && !strings.HasPrefix(u, "/\\") {
+		return u, true
+	}
+	if strings.HasPrefix(u, "#") {
+		return u, true
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		if parsed.Host == "" {
+			return "", false
+		}
+		return parsed.String(), true
+	case "mailto":
+		return parsed.String(), true
+	}
+	return "", false
+}
+
+func hasWebScheme(s string) bool {
+	l := strings.ToLower(s)
+	return strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://")
+}
+
+// matchBracket returns the index of the bracket closing s[0], or -1.
+func matchBracket(s string, open, close byte) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func countRun(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,298 @@
+// Package markdown renders the Markdown subset used in posts, comments and
+// private messages to HTML. Raw HTML in the input is never passed through:
+// every character of user text is escaped, and the result is run through
+// Sanitize as a second line of defence.
+//
+// Supported: ATX headings, paragraphs (single newlines become <br>),
+// bullet and numbered lists (nested by indentation), fenced and indented code
+// blocks, block quotes, horizontal rules, inline code, links and autolinks,
+// *emphasis*, **strong** and ~~strikethrough~~.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Nesting limit for block quotes and lists; deeper input is rendered as text.
+const maxDepth = 8
+
+var (
+	headingRe  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	ruleRe     = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	bulletRe   = regexp.MustCompile(`^( {0,3})([-*+])[ \t]+(.*)$`)
+	orderedRe  = regexp.MustCompile(`^( {0,3})(\d{1,9})[.)][ \t]+(.*)$`)
+	fenceRe    = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*)$")
+	quoteRe    = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	langRe     = regexp.MustCompile(`^[A-Za-z0-9_+#-]{1,32}$`)
+	indentedRe = regexp.MustCompile(`^(?: {4}|\t)(.*)$`)
+)
+
+// Render converts Markdown to sanitized HTML.
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+
+	var sb strings.Builder
+	renderBlocks(&sb, strings.Split(src, "\n"), 0, false)
+	return Sanitize(sb.String())
+}
+
+// renderBlocks writes the blocks in lines. In tight mode (list items)
+// paragraphs are written without <p> wrappers.
+func renderBlocks(sb *strings.Builder, lines []string, depth int, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			i++
+			continue
+		}
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			i = renderFence(sb, lines, i, m[1], m[2])
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(line); m != nil {
+			level := strconv.Itoa(len(m[1]))
+			sb.WriteString("<h" + level + ">")
+			renderInline(sb, strings.TrimSpace(m[2]), true)
+			sb.WriteString("</h" + level + ">\n")
+			i++
+			continue
+		}
+
+		if ruleRe.MatchString(line) {
+			sb.WriteString("<hr>\n")
+			i++
+			continue
+		}
+
+		if depth < maxDepth && quoteRe.MatchString(line) {
+			var inner []string
+			for ; i < len(lines); i++ {
+				m := quoteRe.FindStringSubmatch(lines[i])
+				if m == nil {
+					break
+				}
+				inner = append(inner, m[1])
+			}
+			sb.WriteString("<blockquote>\n")
+			renderBlocks(sb, inner, depth+1, false)
+			sb.WriteString("</blockquote>\n")
+			continue
+		}
+
+		if depth < maxDepth && isListItem(line) {
+			i = renderList(sb, lines, i, depth)
+			continue
+		}
+
+		if !tight && indentedRe.MatchString(line) {
+			var code []string
+			for ; i < len(lines); i++ {
+				if m := indentedRe.FindStringSubmatch(lines[i]); m != nil {
+					code = append(code, m[1])
+				} else if strings.TrimSpace(lines[i]) == "" {
+					code = append(code, "")
+				} else {
+					break
+				}
+			}
+			for len(code) > 0 && code[len(code)-1] == "" {
+				code = code[:len(code)-1]
+			}
+			sb.WriteString("<pre><code>")
+			writeEscaped(sb, strings.Join(code, "\n"))
+			sb.WriteString("</code></pre>\n")
+			continue
+		}
+
+		var para []string
+		for ; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" || (len(para) > 0 && startsBlock(l)) {
+				break
+			}
+			para = append(para, strings.TrimSpace(l))
+		}
+		if !tight {
+			sb.WriteString("<p>")
+		}
+		renderInline(sb, strings.Join(para, "\n"), true)
+		if !tight {
+			sb.WriteString("</p>")
+		}
+		sb.WriteString("\n")
+	}
+}
+
+func startsBlock(line string) bool {
+	return fenceRe.MatchString(line) || headingRe.MatchString(line) || ruleRe.MatchString(line) ||
+		quoteRe.MatchString(line) || isListItem(line)
+}
+
+func isListItem(line string) bool {
+	return bulletRe.MatchString(line) || orderedRe.MatchString(line)
+}
+
+// renderFence writes a fenced code block opened at lines[start] and returns
+// the index after its closing fence (or the end of input).
+func renderFence(sb *strings.Builder, lines []string, start int, fence, info string) int {
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		t := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	sb.WriteString("<pre><code")
+	if lang := strings.Fields(info); len(lang) > 0 && langRe.MatchString(lang[0]) {
+		sb.WriteString(` class="language-`)
+		writeEscaped(sb, lang[0])
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(">")
+	writeEscaped(sb, strings.Join(code, "\n"))
+	sb.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderList writes the list starting at lines[start] and returns the index
+// after it. Lines indented past the marker belong to the current item and are
+// rendered as nested blocks, which is how sub-lists are written.
+func renderList(sb *strings.Builder, lines []string, start, depth int) int {
+	ordered := orderedRe.MatchString(lines[start])
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		m := orderedRe.FindStringSubmatch(lines[start])
+		if n, _ := strconv.Atoi(m[2]); n != 1 {
+			sb.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+		} else {
+			sb.WriteString("<ol>\n")
+		}
+	} else {
+		sb.WriteString("<ul>\n")
+	}
+
+	i := start
+	for i < len(lines) {
+		m := listMarker(lines[i], ordered)
+		if m == nil {
+			break
+		}
+		indent := len(m[1])
+		item := []string{m[3]}
+		i++
+
+		for i < len(lines) {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// A blank line continues the item only if indented content follows.
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) > indent && strings.TrimSpace(lines[i+1]) != "" {
+					item = append(item, "")
+					i++
+					continue
+				}
+				break
+			}
+			if leadingSpaces(l) > indent {
+				item = append(item, dedent(l, indent+2))
+				i++
+				continue
+			}
+			if startsBlock(l) {
+				break
+			}
+			item = append(item, strings.TrimSpace(l)) // lazy continuation
+			i++
+		}
+
+		// Items with blank lines inside keep their paragraphs apart.
+		loose := false
+		for _, l := range item {
+			loose = loose || l == ""
+		}
+		var body strings.Builder
+		renderBlocks(&body, item, depth+1, !loose)
+		sb.WriteString("<li>")
+		sb.WriteString(strings.TrimRight(body.String(), "\n"))
+		sb.WriteString("</li>\n")
+
+		// Blank lines between items keep the list going.
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) && listMarker(lines[j], ordered) != nil {
+			i = j
+		}
+	}
+
+	sb.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func listMarker(line string, ordered bool) []string {
+	if ordered {
+		return orderedRe.FindStringSubmatch(line)
+	}
+	return bulletRe.FindStringSubmatch(line)
+}
+
+func leadingSpaces(s string) int {
+	n := 0
+	for _, c := range s {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// dedent removes up to n columns of leading whitespace.
+func dedent(s string, n int) string {
+	for n > 0 && len(s) > 0 {
+		switch s[0] {
+		case ' ':
+			n--
+		case '\t':
+			n -= 4
+		default:
+			return s
+		}
+		s = s[1:]
+	}
+	return s
+}
+
+func writeEscaped(sb *strings.Builder, s string) {
+	for _, c := range s {
+		switch c {
+		case '&':
+			sb.WriteString("&amp;")
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '"':
+			sb.WriteString("&#34;")
+		case '\'':
+			sb.WriteString("&#39;")
+		default:
+			sb.WriteRune(c)
+		}
+	}
+}
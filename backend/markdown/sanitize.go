@@ -0,0 +1,157 @@
+package markdown
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Elements Sanitize keeps, with the attributes each may carry.
+var allowedElements = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"blockquote": nil, "pre": nil, "code": {"class"},
+	"em": nil, "strong": nil, "del": nil,
+	"a":    {"href"},
+	"mark": nil,
+}
+
+// Elements dropped together with everything inside them.
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "svg": true, "math": true, "textarea": true,
+	"title": true, "head": true,
+}
+
+var voidElements = map[string]bool{"br": true, "hr": true}
+
+// Sanitize keeps only whitelisted elements and attributes from s. Other tags
+// are removed (their text is kept and escaped), links must pass SafeURL and
+// get rel="nofollow noopener noreferrer", and comments and processing
+// instructions are dropped. Characters XML does not allow are replaced with
+// U+FFFD first; tokenizing stops at the first malformed tag.
+func Sanitize(s string) string {
+	s = strings.Map(xmlChar, s)
+	dec := xml.NewDecoder(strings.NewReader(s))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var sb strings.Builder
+	var open []string // allowed elements currently open
+	skip := 0         // depth inside a dropped element
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Everything written so far has been filtered; drop the rest.
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if skip > 0 || droppedElements[name] {
+				skip++
+				continue
+			}
+			attrs, ok := allowedElements[name]
+			if !ok {
+				continue
+			}
+			sb.WriteString("<" + name)
+			writeAttrs(&sb, name, attrs, t.Attr)
+			sb.WriteString(">")
+			if !voidElements[name] {
+				open = append(open, name)
+			}
+
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if voidElements[name] {
+				continue
+			}
+			// Close back to the matching element; ignore stray end tags.
+			for k := len(open) - 1; k >= 0; k-- {
+				if open[k] == name {
+					for len(open) > k {
+						sb.WriteString("</" + open[len(open)-1] + ">")
+						open = open[:len(open)-1]
+					}
+					break
+				}
+			}
+
+		case xml.CharData:
+			if skip == 0 {
+				writeEscaped(&sb, string(t))
+			}
+		}
+	}
+
+	for k := len(open) - 1; k >= 0; k-- {
+		sb.WriteString("</" + open[k] + ">")
+	}
+	return sb.String()
+}
+
+// xmlChar maps runes outside the XML 1.0 Char production (C0 controls other
+// than tab and newlines, surrogates, U+FFFE, U+FFFF) to U+FFFD.
+func xmlChar(r rune) rune {
+	switch {
+	case r == '\t', r == '\n', r == '\r':
+		return r
+	case r < 0x20, r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF, r > 0x10FFFF:
+		return '\uFFFD'
+	}
+	return r
+}
+
+func writeAttrs(sb *strings.Builder, element string, allowed []string, attrs []xml.Attr) {
+	for _, a := range attrs {
+		name := strings.ToLower(a.Name.Local)
+		if a.Name.Space != "" || !contains(allowed, name) {
+			continue
+		}
+		val := a.Value
+		switch name {
+		case "href":
+			href, ok := SafeURL(val)
+			if !ok {
+				continue
+			}
+			val = href
+		case "class":
+			if !strings.HasPrefix(val, "language-") || !langRe.MatchString(strings.TrimPrefix(val, "language-")) {
+				continue
+			}
+		case "start":
+			if val == "" || strings.Trim(val, "0123456789") != "" || len(val) > 9 {
+				continue
+			}
+		}
+		sb.WriteString(" " + name + `="`)
+		writeEscaped(sb, val)
+		sb.WriteString(`"`)
+	}
+	if element == "a" {
+		sb.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
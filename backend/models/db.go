@@ -9,6 +9,26 @@ import (
 
 var DB *sql.DB
 
+// Columns added after a table was first created. CREATE TABLE IF NOT EXISTS
+// leaves existing tables alone, so these are added on startup when missing.
+var addedColumns = []struct {
+	table, column, definition string
+}{
+	{"users", "role", "TEXT NOT NULL DEFAULT 'user'"},
+	{"posts", "edited_at", "DATETIME DEFAULT NULL"},
+	{"posts", "image_thumbnail", "TEXT DEFAULT NULL"},
+	{"post_drafts", "tags", "TEXT NOT NULL DEFAULT '[]'"},
+	{"post_stats", "views", "INTEGER NOT NULL DEFAULT 0"},
+	{"posts", "pinned_at", "DATETIME DEFAULT NULL"},
+	{"posts", "pinned_category_id", "INTEGER DEFAULT NULL"},
+	{"posts", "locked_at", "DATETIME DEFAULT NULL"},
+	{"categories", "description", "TEXT NOT NULL DEFAULT ''"},
+	{"categories", "position", "INTEGER NOT NULL DEFAULT 0"},
+	{"categories", "archived_at", "DATETIME DEFAULT NULL"},
+	{"comments", "edited_at", "DATETIME DEFAULT NULL"},
+	{"comments", "deleted_at", "DATETIME DEFAULT NULL"},
+}
+
 func InitDB(path string) *sql.DB {
 	var err error
 	DB, err = sql.Open("sqlite3", path)
@@ -22,7 +42,35 @@ func InitDB(path string) *sql.DB {
 	if _, err := DB.Exec(string(schema)); err != nil {
 		log.Fatal("Failed to apply schema:", err)
 	}
+	for _, c := range addedColumns {
+		if err := ensureColumn(c.table, c.column, c.definition); err != nil {
+			log.Fatalf("Failed to add column %s.%s: %v", c.table, c.column, err)
+		}
+	}
+	initSearch()
 	log.Println("Database connected and schema applied")
 	DB.SetMaxOpenConns(1) 
 	return DB
-}
\ No newline at end of file
+}
+
+func ensureColumn(table, column, definition string) error {
+	rows, err := DB.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == column {
+			rows.Close()
+			return nil
+		}
+	}
+	rows.Close()
+
+	_, err = DB.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	return err
+}
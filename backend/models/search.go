@@ -0,0 +1,66 @@
+package models
+
+import (
+	"log"
+)
+
+// FullTextSearch is true when the SQLite driver was built with FTS5
+// (go build -tags sqlite_fts5) and the posts_fts index is in place.
+var FullTextSearch bool
+
+// posts_fts indexes post titles and contents. It is an external-content
+// table, so the triggers keep it in step with every write to posts.
+var searchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+		title, content,
+		content='posts', content_rowid='post_id',
+		tokenize='unicode61 remove_diacritics 2'
+	)`,
+	// rank orders matches by BM25 with title hits weighted above content hits.
+	`INSERT INTO posts_fts(posts_fts, rank) VALUES ('rank', 'bm25(10.0, 1.0)')`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_ai AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts(rowid, title, content) VALUES (new.post_id, new.title, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_ad AFTER DELETE ON posts BEGIN
+		INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_au AFTER UPDATE OF title, content ON posts BEGIN
+		INSERT INTO posts_fts(posts_fts, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+		INSERT INTO posts_fts(rowid, title, content) VALUES (new.post_id, new.title, new.content);
+	END`,
+}
+
+var searchTriggers = []string{"posts_fts_ai", "posts_fts_ad", "posts_fts_au"}
+
+// initSearch sets up posts_fts when FTS5 is available. Without it the
+// triggers are dropped so writes to posts keep working, and the index is
+// rebuilt the next time the server starts with FTS5.
+func initSearch() {
+	var n int
+	DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?`, searchTriggers[0]).Scan(&n)
+	synced := n > 0
+
+	var fts5 bool
+	DB.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+	if !fts5 {
+		log.Println("FTS5 not available (build with -tags sqlite_fts5), post search falls back to LIKE")
+		for _, name := range searchTriggers {
+			if _, err := DB.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				log.Fatal("Failed to drop search trigger:", err)
+			}
+		}
+		return
+	}
+
+	for _, stmt := range searchSchema {
+		if _, err := DB.Exec(stmt); err != nil {
+			log.Fatal("Failed to create search index:", err)
+		}
+	}
+	if !synced {
+		if _, err := DB.Exec(`INSERT INTO posts_fts(posts_fts) VALUES ('rebuild')`); err != nil {
+			log.Fatal("Failed to build search index:", err)
+		}
+	}
+	FullTextSearch = true
+}
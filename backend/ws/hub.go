@@ -12,9 +12,16 @@ type Client struct {
 	Username string
 }
 
+// FilteredMessage is a broadcast that skips the Excluded users.
+type FilteredMessage struct {
+	Excluded map[int]bool
+	Message  []byte
+}
+
 type Hub struct {
 	Clients    map[*Client]bool
 	Broadcast  chan []byte
+	BroadcastFiltered chan FilteredMessage
 	Register   chan *Client
 	Unregister chan *Client
 	UserClients map[int][]*Client 
@@ -24,6 +31,7 @@ func NewHub() *Hub {
 	return &Hub{
 		Clients:     make(map[*Client]bool),
 		Broadcast:   make(chan []byte),
+		BroadcastFiltered: make(chan FilteredMessage),
 		Register:    make(chan *Client),
 		Unregister:  make(chan *Client),
 		UserClients: make(map[int][]*Client),
@@ -40,37 +48,55 @@ func (h *Hub) Run() {
 			}
 			
 		case client := <-h.Unregister:
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client.Send)
-				if client.UserID > 0 {
-					if clients, exists := h.UserClients[client.UserID]; exists {
-						for i, c := range clients {
-							if c == client {
-								h.UserClients[client.UserID] = append(clients[:i], clients[i+1:]...)
-								break
-							}
-						}
-						if len(h.UserClients[client.UserID]) == 0 {
-							delete(h.UserClients, client.UserID)
-						}
-					}
-				}
-			}
+			h.removeClient(client)
 			
 		case message := <-h.Broadcast:
 			for client := range h.Clients {
 				select {
 				case client.Send <- message:
 				default:
-					close(client.Send)
-					delete(h.Clients, client)
+					h.removeClient(client)
+				}
+			}
+
+		case fm := <-h.BroadcastFiltered:
+			for client := range h.Clients {
+				if fm.Excluded[client.UserID] {
+					continue
+				}
+				select {
+				case client.Send <- fm.Message:
+				default:
+					h.removeClient(client)
 				}
 			}
 		}
 	}
 }
 
+// removeClient drops client from Clients and UserClients and closes its
+// Send channel. Only Run may call it.
+func (h *Hub) removeClient(client *Client) {
+	if _, ok := h.Clients[client]; !ok {
+		return
+	}
+	delete(h.Clients, client)
+	close(client.Send)
+	if client.UserID > 0 {
+		if clients, exists := h.UserClients[client.UserID]; exists {
+			for i, c := range clients {
+				if c == client {
+					h.UserClients[client.UserID] = append(clients[:i], clients[i+1:]...)
+					break
+				}
+			}
+			if len(h.UserClients[client.UserID]) == 0 {
+				delete(h.UserClients, client.UserID)
+			}
+		}
+	}
+}
+
 func (h *Hub) SendToUser(userID int, message []byte) {
 	if clients, exists := h.UserClients[userID]; exists {
 		for _, client := range clients {
@@ -95,4 +121,9 @@ func (h *Hub) BroadcastExcept(senderID int, message []byte) {
 			}
 		}
 	}
+}
+
+// BroadcastExceptUsers hands the message to Run, which owns the client maps.
+func (h *Hub) BroadcastExceptUsers(excluded map[int]bool, message []byte) {
+	h.BroadcastFiltered <- FilteredMessage{Excluded: excluded, Message: message}
 }
\ No newline at end of file
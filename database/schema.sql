@@ -14,14 +14,18 @@ CREATE TABLE IF NOT EXISTS users (
     gender TEXT DEFAULT NULL,
     account_description TEXT DEFAULT NULL, -- Optional 
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    role TEXT NOT NULL DEFAULT 'user', -- 'user', 'moderator' or 'admin'
 
     CHECK(gender IN ('female', 'male'))
 );
 
--- Predefined categories
+-- Predefined categories, managed by admins
 CREATE TABLE IF NOT EXISTS categories (
     category_id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT UNIQUE NOT NULL
+    name TEXT UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0, -- display order, then name
+    archived_at DATETIME DEFAULT NULL -- archived categories take no new posts
 );
 
 CREATE TABLE IF NOT EXISTS posts (
@@ -29,11 +33,75 @@ CREATE TABLE IF NOT EXISTS posts (
     user_id INTEGER NOT NULL,
     title TEXT NOT NULL,
     content TEXT NOT NULL,
-    image TEXT  DEFAULT NULL, -- Optional
+    image TEXT  DEFAULT NULL, -- Optional, uploaded under /assets/uploads/posts
+    image_thumbnail TEXT DEFAULT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    edited_at DATETIME DEFAULT NULL,
+    pinned_at DATETIME DEFAULT NULL, -- set by moderators; listed first while set
+    pinned_category_id INTEGER DEFAULT NULL, -- NULL pins to the whole feed
+    locked_at DATETIME DEFAULT NULL, -- locked posts take no comments, reactions or votes
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE RESTRICT
 );
 
+-- Prior versions of a post, written each time it is edited
+CREATE TABLE IF NOT EXISTS post_revisions (
+    revision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    edited_by INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Optional poll attached to a post (at most one per post)
+CREATE TABLE IF NOT EXISTS polls (
+    poll_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL UNIQUE,
+    question TEXT NOT NULL,
+    multiple_choice BOOLEAN NOT NULL DEFAULT FALSE,
+    closes_at DATETIME DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    option_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    poll_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    FOREIGN KEY (poll_id) REFERENCES polls(poll_id) ON DELETE CASCADE
+);
+
+-- A user's ballot: one row per chosen option, a single row for single-choice polls
+CREATE TABLE IF NOT EXISTS poll_votes (
+    poll_id INTEGER NOT NULL,
+    option_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (poll_id, user_id, option_id),
+    FOREIGN KEY (poll_id) REFERENCES polls(poll_id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id) REFERENCES poll_options(option_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Unpublished posts. A draft with publish_at set is published by the
+-- background publisher once that time has passed, then deleted.
+CREATE TABLE IF NOT EXISTS post_drafts (
+    draft_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    categories TEXT NOT NULL DEFAULT '[]', -- JSON array of category ids
+    tags TEXT NOT NULL DEFAULT '[]', -- JSON array of tag names
+    publish_at DATETIME DEFAULT NULL,
+    publish_error TEXT DEFAULT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
 -- Junction table for posts (many-to-many)
 CREATE TABLE IF NOT EXISTS post_categories (
     post_id INTEGER NOT NULL,
@@ -43,6 +111,38 @@ CREATE TABLE IF NOT EXISTS post_categories (
     PRIMARY KEY (post_id, category_id)
 );
 
+-- Free-form tags, stored normalized (see normalizeTag)
+CREATE TABLE IF NOT EXISTS tags (
+    tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+    post_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(tag_id),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag ON post_tags(tag_id);
+
+-- Posts saved by a user to read later; folder '' means unfiled.
+CREATE TABLE IF NOT EXISTS bookmarks (
+    bookmark_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    folder TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    UNIQUE (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_folder ON bookmarks(user_id, folder);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_post ON bookmarks(post_id);
+
 CREATE TABLE IF NOT EXISTS comments (
     comment_id INTEGER PRIMARY KEY AUTOINCREMENT,
     post_id INTEGER NOT NULL,
@@ -50,6 +150,8 @@ CREATE TABLE IF NOT EXISTS comments (
     content TEXT NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     parent_id INTEGER DEFAULT NULL,
+    edited_at DATETIME DEFAULT NULL,
+    deleted_at DATETIME DEFAULT NULL, -- set on tombstones: deleted comments kept for their replies
     FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
     FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE RESTRICT,
     FOREIGN KEY (parent_id) REFERENCES comments(comment_id) ON DELETE CASCADE
@@ -80,6 +182,155 @@ CREATE TABLE IF NOT EXISTS reactions (
     UNIQUE (user_id, comment_id)
 );
 
+-- Per-post counters for the feed's sort modes, kept current by the triggers
+-- below so sorting never has to aggregate reactions or comments.
+CREATE TABLE IF NOT EXISTS post_stats (
+    post_id INTEGER PRIMARY KEY,
+    likes INTEGER NOT NULL DEFAULT 0,
+    dislikes INTEGER NOT NULL DEFAULT 0,
+    comments INTEGER NOT NULL DEFAULT 0,
+    last_activity_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    views INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE
+);
+
+CREATE TRIGGER IF NOT EXISTS post_stats_post_ai AFTER INSERT ON posts BEGIN
+    INSERT OR IGNORE INTO post_stats (post_id, last_activity_at) VALUES (new.post_id, COALESCE(new.created_at, CURRENT_TIMESTAMP));
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_post_ad AFTER DELETE ON posts BEGIN
+    DELETE FROM post_stats WHERE post_id = old.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_reaction_ai AFTER INSERT ON reactions WHEN new.post_id IS NOT NULL BEGIN
+    UPDATE post_stats SET likes = likes + (new.type = 'like'), dislikes = dislikes + (new.type = 'dislike')
+    WHERE post_id = new.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_reaction_ad AFTER DELETE ON reactions WHEN old.post_id IS NOT NULL BEGIN
+    UPDATE post_stats SET likes = likes - (old.type = 'like'), dislikes = dislikes - (old.type = 'dislike')
+    WHERE post_id = old.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_reaction_au AFTER UPDATE OF type ON reactions WHEN new.post_id IS NOT NULL BEGIN
+    UPDATE post_stats
+    SET likes = likes - (old.type = 'like') + (new.type = 'like'),
+        dislikes = dislikes - (old.type = 'dislike') + (new.type = 'dislike')
+    WHERE post_id = new.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_comment_ai AFTER INSERT ON comments BEGIN
+    UPDATE post_stats SET comments = comments + 1, last_activity_at = COALESCE(new.created_at, CURRENT_TIMESTAMP)
+    WHERE post_id = new.post_id;
+END;
+
+-- Tombstoned comments (deleted_at set, kept for their replies) are not
+-- counted. The triggers that depend on that are dropped and recreated so
+-- databases from before tombstones pick up the new definitions.
+DROP TRIGGER IF EXISTS post_stats_comment_ad;
+CREATE TRIGGER post_stats_comment_ad AFTER DELETE ON comments WHEN old.deleted_at IS NULL BEGIN
+    UPDATE post_stats SET comments = comments - 1 WHERE post_id = old.post_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS post_stats_comment_tombstone AFTER UPDATE OF deleted_at ON comments
+WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+    UPDATE post_stats SET comments = comments - 1 WHERE post_id = new.post_id;
+END;
+
+-- One row per reader of a post: "u:<user_id>" or "a:<hash>" for anonymous
+-- readers. viewed_at is the latest view, first views bump post_stats.views.
+CREATE TABLE IF NOT EXISTS post_views (
+    post_id INTEGER NOT NULL,
+    viewer TEXT NOT NULL,
+    viewed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, viewer)
+);
+
+CREATE TRIGGER IF NOT EXISTS post_stats_view_ai AFTER INSERT ON post_views BEGIN
+    UPDATE post_stats SET views = views + 1 WHERE post_id = new.post_id;
+END;
+
+-- Backfill posts written before post_stats existed
+INSERT INTO post_stats (post_id, likes, dislikes, comments, last_activity_at)
+SELECT p.post_id,
+    (SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.post_id AND r.comment_id IS NULL AND r.type = 'like'),
+    (SELECT COUNT(*) FROM reactions r WHERE r.post_id = p.post_id AND r.comment_id IS NULL AND r.type = 'dislike'),
+    (SELECT COUNT(*) FROM comments c WHERE c.post_id = p.post_id),
+    COALESCE((SELECT MAX(c.created_at) FROM comments c WHERE c.post_id = p.post_id), p.created_at)
+FROM posts p
+WHERE NOT EXISTS (SELECT 1 FROM post_stats s WHERE s.post_id = p.post_id);
+
+-- Per-category totals for the forum index, kept current like post_stats.
+CREATE TABLE IF NOT EXISTS category_stats (
+    category_id INTEGER PRIMARY KEY,
+    posts INTEGER NOT NULL DEFAULT 0,
+    comments INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
+);
+
+CREATE TRIGGER IF NOT EXISTS category_stats_category_ai AFTER INSERT ON categories BEGIN
+    INSERT OR IGNORE INTO category_stats (category_id) VALUES (new.category_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS category_stats_category_ad AFTER DELETE ON categories BEGIN
+    DELETE FROM category_stats WHERE category_id = old.category_id;
+END;
+
+-- Tombstones are left out as in post_stats; see post_stats_comment_ad.
+DROP TRIGGER IF EXISTS category_stats_link_ai;
+CREATE TRIGGER category_stats_link_ai AFTER INSERT ON post_categories BEGIN
+    UPDATE category_stats
+    SET posts = posts + 1,
+        comments = comments + (SELECT COUNT(*) FROM comments c WHERE c.post_id = new.post_id AND c.deleted_at IS NULL)
+    WHERE category_id = new.category_id;
+END;
+
+DROP TRIGGER IF EXISTS category_stats_link_ad;
+CREATE TRIGGER category_stats_link_ad AFTER DELETE ON post_categories BEGIN
+    UPDATE category_stats
+    SET posts = posts - 1,
+        comments = comments - (SELECT COUNT(*) FROM comments c WHERE c.post_id = old.post_id AND c.deleted_at IS NULL)
+    WHERE category_id = old.category_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS category_stats_comment_ai AFTER INSERT ON comments BEGIN
+    UPDATE category_stats SET comments = comments + 1
+    WHERE category_id IN (SELECT category_id FROM post_categories WHERE post_id = new.post_id);
+END;
+
+DROP TRIGGER IF EXISTS category_stats_comment_ad;
+CREATE TRIGGER category_stats_comment_ad AFTER DELETE ON comments WHEN old.deleted_at IS NULL BEGIN
+    UPDATE category_stats SET comments = comments - 1
+    WHERE category_id IN (SELECT category_id FROM post_categories WHERE post_id = old.post_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS category_stats_comment_tombstone AFTER UPDATE OF deleted_at ON comments
+WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL BEGIN
+    UPDATE category_stats SET comments = comments - 1
+    WHERE category_id IN (SELECT category_id FROM post_categories WHERE post_id = new.post_id);
+END;
+
+-- Backfill categories created before category_stats existed
+INSERT INTO category_stats (category_id, posts, comments)
+SELECT cat.category_id,
+    (SELECT COUNT(*) FROM post_categories pc WHERE pc.category_id = cat.category_id),
+    (SELECT COUNT(*) FROM post_categories pc JOIN comments c ON c.post_id = pc.post_id WHERE pc.category_id = cat.category_id)
+FROM categories cat
+WHERE NOT EXISTS (SELECT 1 FROM category_stats s WHERE s.category_id = cat.category_id);
+
+-- Where each user last caught up with a category: the newest post id in it
+-- when they last opened its feed. Posts after that id count as new.
+CREATE TABLE IF NOT EXISTS category_visits (
+    user_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    last_post_id INTEGER NOT NULL DEFAULT 0,
+    visited_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
+);
+
 -- Private messages table
 CREATE TABLE IF NOT EXISTS private_messages (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -93,6 +344,25 @@ CREATE TABLE IF NOT EXISTS private_messages (
     FOREIGN KEY (to_user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
+-- Messages a user starred for themselves
+CREATE TABLE IF NOT EXISTS starred_messages (
+    user_id INTEGER NOT NULL,
+    message_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, message_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES private_messages(id) ON DELETE CASCADE
+);
+
+-- Messages pinned to a conversation (visible to both participants)
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    message_id INTEGER PRIMARY KEY,
+    pinned_by INTEGER NOT NULL,
+    pinned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES private_messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (pinned_by) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
 -- Typing indicators table (optional, can use WebSocket only)
 CREATE TABLE IF NOT EXISTS typing_indicators (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -104,6 +374,95 @@ CREATE TABLE IF NOT EXISTS typing_indicators (
     FOREIGN KEY (target_user_id) REFERENCES users(user_id) ON DELETE CASCADE
 );
 
+/**************************************
+ *  MENTIONS & NOTIFICATIONS
+ **************************************/
+
+-- @username references in posts and comments
+CREATE TABLE IF NOT EXISTS mentions (
+    mention_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL, -- mentioned user
+    author_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    comment_id INTEGER DEFAULT NULL, -- NULL when the mention is in the post itself
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(comment_id) ON DELETE CASCADE
+);
+
+-- Persistent notification center (survives the user being offline)
+CREATE TABLE IF NOT EXISTS notifications (
+    notification_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL, -- recipient
+    actor_id INTEGER NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('comment', 'reply', 'reaction', 'mention', 'message')),
+    post_id INTEGER DEFAULT NULL,
+    comment_id INTEGER DEFAULT NULL,
+    message_id INTEGER DEFAULT NULL,
+    detail TEXT DEFAULT NULL, -- e.g. reaction type
+    excerpt TEXT NOT NULL DEFAULT '',
+    is_read BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(post_id) ON DELETE CASCADE,
+    FOREIGN KEY (comment_id) REFERENCES comments(comment_id) ON DELETE CASCADE,
+    FOREIGN KEY (message_id) REFERENCES private_messages(id) ON DELETE CASCADE
+);
+
+/**************************************
+ *  PREFERENCES
+ **************************************/
+
+-- Per-kind delivery: 'live' (stored + pushed), 'list' (stored only), 'off'
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    delivery TEXT NOT NULL DEFAULT 'live' CHECK (delivery IN ('live', 'list', 'off')),
+    PRIMARY KEY (user_id, kind),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS muted_conversations (
+    user_id INTEGER NOT NULL,
+    other_user_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, other_user_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (other_user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS muted_categories (
+    user_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
+);
+
+-- Categories a user follows; their posts make up /api/feed
+CREATE TABLE IF NOT EXISTS category_follows (
+    user_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category_id),
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(category_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_follows_category ON category_follows(category_id);
+
+-- Opt-in e-mail digest of unread messages and notifications
+CREATE TABLE IF NOT EXISTS email_digests (
+    user_id INTEGER PRIMARY KEY,
+    enabled BOOLEAN DEFAULT FALSE,
+    last_sent_at DATETIME DEFAULT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
 /**************************************
  *  AUTHENTICATION
  *  Sessions, Password Resets
@@ -128,6 +487,21 @@ CREATE TABLE IF NOT EXISTS user_activity (
 CREATE INDEX IF NOT EXISTS idx_private_messages_users ON private_messages(from_user_id, to_user_id);
 CREATE INDEX IF NOT EXISTS idx_private_messages_created_at ON private_messages(created_at);
 CREATE INDEX IF NOT EXISTS idx_private_messages_read ON private_messages(is_read, to_user_id);
+CREATE INDEX IF NOT EXISTS idx_starred_messages_user ON starred_messages(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_mentions_user ON mentions(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, notification_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(user_id, is_read);
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at);
+CREATE INDEX IF NOT EXISTS idx_post_categories_category ON post_categories(category_id, post_id);
+CREATE INDEX IF NOT EXISTS idx_post_stats_activity ON post_stats(last_activity_at);
+CREATE INDEX IF NOT EXISTS idx_post_views_viewed_at ON post_views(viewed_at);
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments(parent_id, comment_id);
+CREATE INDEX IF NOT EXISTS idx_reactions_created_at ON reactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_post_drafts_user ON post_drafts(user_id, updated_at);
+CREATE INDEX IF NOT EXISTS idx_post_drafts_publish ON post_drafts(publish_at);
+CREATE INDEX IF NOT EXISTS idx_poll_options_poll ON poll_options(poll_id, position);
+CREATE INDEX IF NOT EXISTS idx_poll_votes_option ON poll_votes(option_id);
 
 
 /**************************************
@@ -12,6 +12,9 @@ if (!window.__commentsWS) {
     if (msg && msg.type === "comment.reaction") {
       window.dispatchEvent(new CustomEvent("ws:comment.reaction", { detail: msg.data }));
     }
+    if (msg && (msg.type === "comment.updated" || msg.type === "comment.deleted")) {
+      window.dispatchEvent(new CustomEvent(`ws:${msg.type}`, { detail: msg.data }));
+    }
   });
   ws.addEventListener("close", () => setTimeout(() => (window.__commentsWS = null), 1000));
   window.__commentsWS = ws;
@@ -121,8 +124,77 @@ function mountCommentPane(section, postId) {
     pageSize: 10,
     firstLoad: true,
     boundScroll: false,
+    replyTo: 0,
+  };
+
+  const defaultPlaceholder = input.placeholder;
+  const setReplyTo = (id, username) => {
+    state.replyTo = id;
+    input.placeholder = id ? `Replying to @${username}… (Esc to cancel)` : defaultPlaceholder;
   };
 
+  section.addEventListener("click", async (e) => {
+    const replyBtn = e.target.closest(".comment-reply-btn");
+    if (replyBtn) {
+      setReplyTo(parseInt(replyBtn.dataset.id, 10), replyBtn.dataset.username);
+      input.focus();
+      return;
+    }
+
+    const editBtn = e.target.closest(".comment-edit-btn");
+    if (editBtn) {
+      const row = editBtn.closest(".comment-row");
+      const current = row.querySelector(":scope > .comment-body").dataset.raw || "";
+      const content = prompt("Edit comment", current);
+      if (content === null || !content.trim() || content === current) return;
+      try {
+        const res = await fetch(`/api/comments/${editBtn.dataset.id}`, {
+          method: "PATCH",
+          headers: { "Content-Type": "application/json", "Accept": "application/json" },
+          body: JSON.stringify({ content })
+        }).then(r => r.json());
+        if (res && res.success && res.data) applyCommentUpdate(list, res.data);
+        else console.warn("Failed to edit comment:", res);
+      } catch (err) {
+        console.error("Failed to edit comment:", err);
+      }
+      return;
+    }
+
+    const deleteBtn = e.target.closest(".comment-delete-btn");
+    if (deleteBtn) {
+      if (!confirm("Delete this comment?")) return;
+      try {
+        const res = await fetch(`/api/comments/${deleteBtn.dataset.id}`, { method: "DELETE", headers: { "Accept": "application/json" } }).then(r => r.json());
+        if (!res || !res.success) console.warn("Failed to delete comment:", res);
+      } catch (err) {
+        console.error("Failed to delete comment:", err);
+      }
+      return;
+    }
+
+    const moreBtn = e.target.closest(".comment-more-replies");
+    if (!moreBtn || moreBtn.disabled) return;
+    moreBtn.disabled = true;
+    const params = new URLSearchParams({ parent_id: moreBtn.dataset.parent, limit: "10" });
+    if (moreBtn.dataset.before !== "0") params.set("before_id", moreBtn.dataset.before);
+    try {
+      const res = await apiGet(`/api/posts/${postId}/comments?` + params.toString());
+      const items = (res && res.data) || [];
+      const box = moreBtn.parentElement;
+      const fresh = items.filter(c => !box.querySelector(`.comment-row[data-id="${c.comment_id}"]`));
+      prependComments(box, fresh);
+      const hidden = parseInt(moreBtn.textContent.match(/\d+/)[0], 10) - items.length;
+      moreBtn.remove();
+      if (hidden > 0 && items.length) {
+        box.insertBefore(buildMoreRepliesButton(moreBtn.dataset.parent, hidden, items[0].comment_id), box.firstChild);
+      }
+    } catch (err) {
+      console.error("Failed to load replies:", err);
+      moreBtn.disabled = false;
+    }
+  });
+
   section.addEventListener("comments:load-initial", async () => {
     if (state.loading) return;
     if (!list.dataset.loadedOnce) { state.cursor = 0; state.done = false; }
@@ -136,6 +208,10 @@ function mountCommentPane(section, postId) {
   input.addEventListener("compositionend",  () => { composing = false; });
 
   input.addEventListener("keydown", async (e) => {
+    if (e.key === "Escape" && state.replyTo) {
+      setReplyTo(0);
+      return;
+    }
     if (e.key !== "Enter" || e.shiftKey) return;
     if (composing) return;        
     e.preventDefault();
@@ -147,9 +223,12 @@ function mountCommentPane(section, postId) {
     input.disabled = true;
 
     try {
-      const res = await apiPost(`/api/posts/${postId}/comments`, { content });
+      const body = { content };
+      if (state.replyTo) body.parent_id = state.replyTo;
+      const res = await apiPost(`/api/posts/${postId}/comments`, body);
       if (res && res.success) {
         input.value = "";
+        setReplyTo(0);
         list.innerHTML = "";
         state.cursor = 0;
         state.done = false;
@@ -178,7 +257,7 @@ function mountCommentPane(section, postId) {
     state.loading = true;
     const prevH = list.scrollHeight;
 
-    const params = new URLSearchParams({ limit: String(state.pageSize) });
+    const params = new URLSearchParams({ limit: String(state.pageSize), depth: "3" });
     if (state.cursor > 0) params.set("before_id", String(state.cursor));
 
     try {
@@ -211,26 +290,14 @@ function mountCommentPane(section, postId) {
 
     const nearBottom = (list.scrollHeight - list.scrollTop - list.clientHeight) < 60;
 
-    const row = document.createElement("div");
-    row.className = "comment-row";
-    row.dataset.id = d.comment_id;
-    row.style.cssText = "display:flex; flex-direction:column; gap:2px; padding:6px 4px; border-bottom:1px solid #222;";
-    row.innerHTML = `
-      <div style="display:flex; gap:6px; color:#9aa; font-size:12px;">
-        <b>@${escapeHTML(d.username || "user")}</b>
-        <span>•</span>
-        <span>${timeAgo(d.created_at || Date.now())}</span>
-      </div>
-      <div style="color:#000000; font-size:14px;">${escapeHTML(d.content || "")}</div>
-      <div style="display:flex; gap:8px; color:#9aa; font-size:12px;">
-        <button class="comment-react-btn comment-like-btn" data-id="${d.comment_id}" data-type="like" style="background:none; border:none; cursor:pointer; color:#9aa; padding:2px 6px; border-radius:4px;">
-          👍 <span class="count">0</span>
-        </button>
-        <button class="comment-react-btn comment-dislike-btn" data-id="${d.comment_id}" data-type="dislike" style="background:none; border:none; cursor:pointer; color:#9aa; padding:2px 6px; border-radius:4px;">
-          👎 <span class="count">0</span>
-        </button>
-      </div>
-    `;
+    const row = buildCommentRow({ ...d, likes: 0, dislikes: 0, reply_count: 0 });
+    if (d.parent_id) {
+      const parent = list.querySelector(`.comment-row[data-id="${d.parent_id}"]`);
+      if (!parent) return;
+      parent.querySelector(":scope > .comment-replies").appendChild(row);
+      list.dataset.loadedOnce = "1";
+      return;
+    }
     list.appendChild(row);
 
     if (nearBottom) list.scrollTop = list.scrollHeight;
@@ -253,44 +320,32 @@ function mountCommentPane(section, postId) {
     dislikeCountEl.textContent = d.dislikes || 0;
   };
 
+  const onLiveUpdate = (e) => {
+    const d = e.detail || {};
+    if (d.post_id === postId) applyCommentUpdate(list, d);
+  };
+
+  const onLiveDelete = (e) => {
+    const d = e.detail || {};
+    if (d.post_id !== postId) return;
+    const row = list.querySelector(`.comment-row[data-id="${d.comment_id}"]`);
+    if (!row) return;
+    if (d.tombstone) {
+      row.replaceWith(buildCommentRow({ ...d, deleted: true, likes: 0, dislikes: 0 }, row));
+    } else {
+      row.remove();
+    }
+  };
+
   window.addEventListener("ws:comment.created", onLiveComment);
   window.addEventListener("ws:comment.reaction", onLiveReaction);
+  window.addEventListener("ws:comment.updated", onLiveUpdate);
+  window.addEventListener("ws:comment.deleted", onLiveDelete);
 }
 
 function prependComments(container, comments) {
   const frag = document.createDocumentFragment();
-  comments.forEach(c => {
-    const row = document.createElement("div");
-    row.className = "comment-row";
-    row.dataset.id = c.comment_id; 
-    row.style.cssText = "display:flex; flex-direction:column; gap:2px; padding:6px 4px; border-bottom:1px solid #222;";
-    
-    
-    const liked = c.my_reaction === "like";
-    const disliked = c.my_reaction === "dislike";
-    
-    row.innerHTML = `
-      <div style="display:flex; gap:6px; color:#9aa; font-size:12px;">
-        <b>@${escapeHTML(c.username)}</b>
-        <span>•</span>
-        <span>${timeAgo(c.created_at || c.createdAt)}</span>
-      </div>
-      <div style="color:#000000; font-size:14px;">${escapeHTML(c.content)}</div>
-      <div style="display:flex; gap:8px; color:#9aa; font-size:12px;">
-        <button class="comment-react-btn comment-like-btn ${liked ? 'is-active' : ''}" 
-                data-id="${c.comment_id}" data-type="like" 
-                style="background:none; border:none; cursor:pointer; color:${liked ? '#4f46e5' : '#9aa'}; padding:2px 6px; border-radius:4px;">
-          👍 <span class="count">${c.likes}</span>
-        </button>
-        <button class="comment-react-btn comment-dislike-btn ${disliked ? 'is-active' : ''}" 
-                data-id="${c.comment_id}" data-type="dislike" 
-                style="background:none; border:none; cursor:pointer; color:${disliked ? '#4f46e5' : '#9aa'}; padding:2px 6px; border-radius:4px;">
-          👎 <span class="count">${c.dislikes}</span>
-        </button>
-      </div>
-    `;
-    frag.appendChild(row);
-  });
+  comments.forEach(c => frag.appendChild(buildCommentRow(c)));
   
   if (container.firstChild) {
     container.insertBefore(frag, container.firstChild);
@@ -298,3 +353,79 @@ function prependComments(container, comments) {
     container.appendChild(frag);
   }
 }
+
+function applyCommentUpdate(list, d) {
+  const row = list.querySelector(`.comment-row[data-id="${d.comment_id}"]`);
+  if (!row) return;
+  const body = row.querySelector(":scope > .comment-body");
+  body.innerHTML = d.content_html ?? escapeHTML(d.content || "");
+  body.dataset.raw = d.content || "";
+  const stamp = row.querySelector(":scope > .comment-meta .comment-edited");
+  if (stamp && d.edited_at) stamp.textContent = "(edited)";
+}
+
+// buildCommentRow renders c and its embedded replies. When replacing an
+// existing row (a comment turned tombstone), its loaded replies are kept.
+function buildCommentRow(c, previous) {
+  const row = document.createElement("div");
+  row.className = "comment-row";
+  row.dataset.id = c.comment_id; 
+  row.style.cssText = "display:flex; flex-direction:column; gap:2px; padding:6px 4px; border-bottom:1px solid #222;";
+  
+  
+  const liked = c.my_reaction === "like";
+  const disliked = c.my_reaction === "dislike";
+  const mine = !c.deleted && c.user_id && c.user_id === window.currentUserId;
+  
+  row.innerHTML = `
+    <div class="comment-meta" style="display:flex; gap:6px; color:#9aa; font-size:12px;">
+      <b>${c.deleted ? "[deleted]" : "@" + escapeHTML(c.username || "user")}</b>
+      <span>•</span>
+      <span>${timeAgo(c.created_at || c.createdAt || Date.now())}</span>
+      <span class="comment-edited">${c.edited_at && !c.deleted ? "(edited)" : ""}</span>
+    </div>
+    <div class="comment-body" style="color:${c.deleted ? '#888' : '#000000'}; font-size:14px; ${c.deleted ? 'font-style:italic;' : ''}">${c.content_html ?? escapeHTML(c.content || "")}</div>
+    <div class="comment-actions" style="display:${c.deleted ? 'none' : 'flex'}; gap:8px; color:#9aa; font-size:12px;">
+      <button class="comment-react-btn comment-like-btn ${liked ? 'is-active' : ''}" 
+              data-id="${c.comment_id}" data-type="like" 
+              style="background:none; border:none; cursor:pointer; color:${liked ? '#4f46e5' : '#9aa'}; padding:2px 6px; border-radius:4px;">
+        👍 <span class="count">${c.likes}</span>
+      </button>
+      <button class="comment-react-btn comment-dislike-btn ${disliked ? 'is-active' : ''}" 
+              data-id="${c.comment_id}" data-type="dislike" 
+              style="background:none; border:none; cursor:pointer; color:${disliked ? '#4f46e5' : '#9aa'}; padding:2px 6px; border-radius:4px;">
+        👎 <span class="count">${c.dislikes}</span>
+      </button>
+      <button class="comment-reply-btn" data-id="${c.comment_id}" data-username="${escapeHTML(c.username || "user")}"
+              style="background:none; border:none; cursor:pointer; color:#9aa; padding:2px 6px; border-radius:4px;">
+        ↩ Reply
+      </button>
+      ${mine ? `
+      <button class="comment-edit-btn" data-id="${c.comment_id}" style="background:none; border:none; cursor:pointer; color:#9aa; padding:2px 6px; border-radius:4px;">✏️ Edit</button>
+      <button class="comment-delete-btn" data-id="${c.comment_id}" style="background:none; border:none; cursor:pointer; color:#9aa; padding:2px 6px; border-radius:4px;">🗑 Delete</button>` : ""}
+    </div>
+    <div class="comment-replies" style="margin-left:16px; border-left:2px solid #333; padding-left:6px;"></div>
+  `;
+
+  row.querySelector(".comment-body").dataset.raw = c.deleted ? "" : (c.content || "");
+  const box = row.querySelector(".comment-replies");
+  if (previous) {
+    box.replaceWith(previous.querySelector(":scope > .comment-replies"));
+    return row;
+  }
+  const replies = c.replies || [];
+  const hidden = (c.reply_count || 0) - replies.length;
+  if (hidden > 0) box.appendChild(buildMoreRepliesButton(c.comment_id, hidden, replies[0]?.comment_id || 0));
+  replies.forEach(r => box.appendChild(buildCommentRow(r)));
+  return row;
+}
+
+function buildMoreRepliesButton(parentId, hidden, beforeId) {
+  const btn = document.createElement("button");
+  btn.className = "comment-more-replies";
+  btn.dataset.parent = parentId;
+  btn.dataset.before = beforeId;
+  btn.style.cssText = "background:none; border:none; cursor:pointer; color:#4f46e5; font-size:12px; padding:2px 0; text-align:left;";
+  btn.textContent = `Show ${hidden} ${beforeId ? "earlier " : ""}${hidden === 1 ? "reply" : "replies"}`;
+  return btn;
+}
@@ -2,7 +2,7 @@
 import { apiGet, apiPost, $, $$, debounce, throttle, timeAgo } from "./utils.js";
 
 const state = {
-  page: 1,
+  cursor: "",
   limit: 10,
   loading: false,
   done: false,
@@ -19,9 +19,8 @@ document.addEventListener("DOMContentLoaded", () => {
   mountComposer();
   bindAddButton();
   bindInfiniteScroll();
-  loadCategoriesIntoFilter().then(() => {
-    resetFeed();
-  });
+  loadCategoriesIntoFilter().then(() => resetFeed()).then(openPostFromHash);
+  window.addEventListener("hashchange", openPostFromHash);
 });
 
 function bootRealtime() {
@@ -43,6 +42,11 @@ function bootRealtime() {
         case "post.reaction":
           applyReactionCountsInline(msg.data);
           break;
+
+        case "category.updated":
+          loadCategoriesIntoFilter();
+          loadCategoriesForComposer();
+          break;
           
         case "comment.created":
         window.dispatchEvent(new CustomEvent("ws:comment.created", { detail: msg.data }));
@@ -100,6 +104,7 @@ function injectStylesForComposer() {
   .post-title { font-weight: 700; font-size: 16px; margin:6px 0; }
   .post-cats { display:flex; gap:6px; flex-wrap:wrap; margin-top:6px; }
   .pill { font-size: 11px; padding:3px 8px; border-radius:999px; background:#1f2937; color:#cbd5e1; border:1px solid #2b3646;}
+  .post-card.is-target { border-color:#4f46e5; }
   `;
   const style = document.createElement("style");
   style.textContent = css;
@@ -290,7 +295,6 @@ function bindInfiniteScroll() {
     if (state.loading || state.done) return;
     const nearBottom = (scroller.scrollTop + scroller.clientHeight) >= (scroller.scrollHeight - 60);
     if (nearBottom) {
-      state.page++;
       fetchAndRenderPosts(true);
     }
   }, 200));
@@ -344,19 +348,68 @@ document.addEventListener("click", async (e) => {
   }
 });
 
+document.addEventListener("click", async (e) => {
+  const btn = e.target.closest(".bookmark-btn");
+  if (!btn) return;
+  const postId = parseInt(btn.dataset.id, 10);
+  const save = !btn.classList.contains("is-active");
+
+  btn.disabled = true;
+  try {
+    const res = save
+      ? await apiPost(`/api/posts/${postId}/bookmark`, {})
+      : await fetch(`/api/posts/${postId}/bookmark`, { method: "DELETE", headers: { "Accept": "application/json" } }).then(r => r.json());
+    if (res && res.success) {
+      btn.classList.toggle("is-active", res.bookmarked);
+      btn.textContent = res.bookmarked ? "Saved" : "Save";
+    }
+  } catch (_) {
+  } finally {
+    btn.disabled = false;
+  }
+});
+
 function resetFeed() {
-  state.page = 1;
+  state.cursor = "";
   state.done = false;
   $(".posts-scroll").innerHTML = "";
-  fetchAndRenderPosts(false);
+  return fetchAndRenderPosts(false);
+}
+
+// Feed links (#post-N) point here. The post is fetched and put on top when
+// it is not among the loaded ones, then scrolled to with its comments open.
+async function openPostFromHash() {
+  const m = /^#post-(\d+)$/.exec(location.hash);
+  if (!m) return;
+  const postId = parseInt(m[1], 10);
+
+  let card = document.getElementById(`post-${postId}`);
+  if (!card) {
+    const res = await apiGet(`/api/posts/${postId}`);
+    if (!res || !res.data) return;
+    const list = $(".posts-scroll");
+    const holder = document.createElement("div");
+    renderPosts([res.data], holder);
+    card = holder.firstElementChild;
+    if (!card) return;
+    list.prepend(card);
+  }
+
+  $$(".post-card.is-target").forEach(c => c.classList.remove("is-target"));
+  card.classList.add("is-target");
+  card.scrollIntoView({ behavior: "smooth", block: "start" });
+  const section = card.querySelector(".comment-section");
+  if (section && section.style.display !== "flex") {
+    card.querySelector(".comment-toggle-btn")?.click();
+  }
 }
 
 async function fetchAndRenderPosts(append) {
   const list = $(".posts-scroll");
   const params = new URLSearchParams({
-    page: String(state.page),
     limit: String(state.limit)
   });
+  if (append && state.cursor) params.set("cursor", state.cursor);
   if (state.currentCategory > 0) params.set("category_id", String(state.currentCategory));
   if (state.search) params.set("search", state.search);
 
@@ -376,7 +429,8 @@ async function fetchAndRenderPosts(append) {
       return;
     }
     renderPosts(posts, list);
-    if (posts.length < state.limit) state.done = true;
+    state.cursor = res.next_cursor || "";
+    if (!res.has_more) state.done = true;
   } catch (e) {
     if (!append) list.innerHTML = `<div style="color:#f87171;padding:12px;">Failed to load posts.</div>`;
   } finally {
@@ -386,20 +440,24 @@ async function fetchAndRenderPosts(append) {
 
 function renderPosts(posts, container) {
   posts.forEach(p => {
+    if (document.getElementById(`post-${p.post_id}`)) return;
     const card = document.createElement("div");
     const liked = p.my_reaction === "like";
     const disliked = p.my_reaction === "dislike";
     card.className = "post-card";
+    card.id = `post-${p.post_id}`;
     card.innerHTML = `
       <div class="post-head">
         <div>@${escapeHTML(p.username)}</div>
         <div>•</div>
         <div>${timeAgo(p.created_at || p.createdAt)}</div>
+        ${p.views ? `<div>•</div><div>${p.views} ${p.views === 1 ? "view" : "views"}</div>` : ""}
       </div>
-      <div class="post-title">${escapeHTML(p.title)}</div>
-      <div class="post-content">${escapeHTML(p.content)}</div>
+      <div class="post-title">${p.pinned ? "📌 " : ""}${p.locked ? "🔒 " : ""}${escapeHTML(p.title)}</div>
+      <div class="post-content">${p.content_html ?? escapeHTML(p.content)}</div>
       <div class="post-cats">
         ${ (p.categories || []).map(c => `<span class="pill">${escapeHTML(c.name)}</span>`).join("") }
+        ${ (p.tags || []).map(t => `<span class="pill pill-tag">#${escapeHTML(t)}</span>`).join("") }
       </div>
       <div class="post-buttons" style="margin-top:8px; display:flex; gap:8px;">
     <button class="react-btn like-btn ${liked ? "is-active": ""}" data-id="${p.post_id}" data-type="like">
@@ -409,6 +467,7 @@ function renderPosts(posts, container) {
       👎 <span class="count">${p.dislikes}</span>
     </button>
     <button class="comment-toggle-btn" data-id="${p.post_id}">Comment</button>
+    <button class="bookmark-btn ${p.bookmarked ? "is-active": ""}" data-id="${p.post_id}">${p.bookmarked ? "Saved" : "Save"}</button>
   </div>
       <div class="comment-section" data-id="${p.post_id}" style="display:none; flex-direction:column; gap:6px; margin-top:8px;">
         <input type="text" placeholder="Write a comment..." style="padding:10px;border-radius:8px;border:1px solid #333;background:#1a1a1a;color:#eee;">
@@ -610,7 +610,7 @@ class PrivateChatManager {
                     <span class="message-sender">You</span>&nbsp;&nbsp;&nbsp;&nbsp;
                     <span class="message-time">${messageTime}</span>
                 </div>
-                <div class="message-text">${escapeHTML(message.content)}</div>
+                <div class="message-text">${message.content_html ?? escapeHTML(message.content)}</div>
             </div>
         `;
         } else {
@@ -626,7 +626,7 @@ class PrivateChatManager {
                     <span class="message-sender">${escapeHTML(senderName)}</span>&nbsp;&nbsp;&nbsp;&nbsp;
                     <span class="message-time">${messageTime}</span>
                 </div>
-                <div class="message-text">${escapeHTML(message.content)}</div>
+                <div class="message-text">${message.content_html ?? escapeHTML(message.content)}</div>
             </div>
         `;
         }